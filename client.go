@@ -0,0 +1,206 @@
+package orbit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client 客户端接口
+type Client interface {
+	Send(protocol uint32, data []byte) error
+	SendContext(ctx context.Context, protocol uint32, data []byte) error
+	SendQueueLen() int
+	Call(ctx context.Context, protocol uint32, data []byte) ([]byte, error)
+	Close()
+	RemoteAddr() string
+}
+
+// client 客户端结构体
+type client struct {
+	*connection
+	calls *calls
+	seq   bool
+}
+
+// clientOptions 加载客户端配置
+func clientOptions(opts []Option) options {
+	// 初始化默认配置
+	o := options{
+		network: "tcp",
+		pool:    1,
+		tasks:   1024,
+		packet:  4096,
+
+		sendQueue:   1024,
+		sendTimeout: 5 * time.Millisecond,
+		writeBatch:  64 * 1024,
+
+		serializer: NewJSONSerializer(),
+	}
+
+	// 加载自定义配置
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// 客户端可以只发送不接收，未设置路由时使用空路由
+	if o.router == nil {
+		o.router = Setup()
+	}
+
+	return o
+}
+
+// Dial 连接服务端
+func Dial(addr string, opts ...Option) (Client, error) {
+	o := clientOptions(opts)
+
+	// 客户端使用流式数据包，不支持数据报网络
+	if strings.HasPrefix(o.network, "udp") {
+		return nil, fmt.Errorf("dial network %s is not supported", o.network)
+	}
+
+	// 建立连接
+	conn, err := net.Dial(o.network, addr)
+	if err != nil {
+		return nil, err
+	}
+	log.Println(fmt.Sprintf("[ CLIENT ] dial to %s %s", o.network, addr))
+
+	if o.tls != nil {
+		// 未指定 ServerName 时使用连接地址的主机名校验服务端证书
+		config := o.tls.Clone()
+		if config.ServerName == "" {
+			if host, _, e := net.SplitHostPort(addr); e == nil {
+				config.ServerName = host
+			}
+		}
+		tc := tls.Client(conn, config)
+		if err = tc.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	return newClient(conn, o), nil
+}
+
+// newClient 在已建立的连接上创建客户端
+func newClient(conn net.Conn, o options) *client {
+	// 启用工作池机制，调用的响应在进入工作池前被拦截
+	cs := &calls{
+		Worker: newWorker(o),
+		wait:   make(map[uint32]chan *Context),
+	}
+	cs.Start()
+
+	c := &client{
+		connection: newConnection(conn, newManager(o), cs, o),
+		calls:      cs,
+		seq:        o.seq,
+	}
+
+	// 开启协程处理读写，连接断开后停止工作池
+	go func() {
+		c.Handle()
+		cs.Stop()
+	}()
+
+	return c
+}
+
+// Call 发送请求并等待对应序列号的响应，超时由 ctx 控制
+func (c *client) Call(ctx context.Context, protocol uint32, data []byte) ([]byte, error) {
+	if !c.seq {
+		return nil, ErrSequenceRequired
+	}
+
+	seq, reply := c.calls.add()
+	defer c.calls.del(seq)
+
+	msg := NewMessagePacket(protocol, data)
+	msg.SetSeq(seq)
+	buff, err := c.pack(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.enqueue(ctx, buff); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-reply:
+		if resp.Protocol() == ProtocolNoRoute && protocol != ProtocolNoRoute {
+			return nil, fmt.Errorf("%w: %d", ErrNoRoute, protocol)
+		}
+		if resp.Protocol() == ProtocolError && protocol != ProtocolError {
+			return nil, fmt.Errorf("protocol %d %w: %s", protocol, ErrRemote, resp.RawData())
+		}
+		return resp.RawData(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf("wait call reply: %w", ErrConnClosed)
+	}
+}
+
+// calls 等待响应的调用表
+type calls struct {
+	Worker
+
+	lock sync.Mutex
+	seq  uint32
+	wait map[uint32]chan *Context
+}
+
+// add 分配序列号并登记调用
+func (cs *calls) add() (uint32, chan *Context) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	// 序列号 0 表示非调用消息，回绕时跳过
+	cs.seq++
+	if cs.seq == 0 {
+		cs.seq++
+	}
+
+	reply := make(chan *Context, 1)
+	cs.wait[cs.seq] = reply
+
+	return cs.seq, reply
+}
+
+// del 移除调用
+func (cs *calls) del(seq uint32) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	delete(cs.wait, seq)
+}
+
+// JoinTaskQueue 响应交给等待中的调用，其余消息加入任务队列
+func (cs *calls) JoinTaskQueue(ctx *Context) {
+	if ctx != nil && ctx.Seq() != 0 {
+		cs.lock.Lock()
+		reply, ok := cs.wait[ctx.Seq()]
+		delete(cs.wait, ctx.Seq())
+		cs.lock.Unlock()
+
+		if ok {
+			// 响应数据交给调用方，不回收缓冲区
+			ctx.buff = nil
+			reply <- ctx
+			ctx.done()
+			return
+		}
+	}
+
+	cs.Worker.JoinTaskQueue(ctx)
+}
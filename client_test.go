@@ -0,0 +1,109 @@
+package orbit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDial(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(append([]byte("echo: "), ctx.RawData()...))
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11112), WithRouter(r))
+	go func() {
+		if e := srv.On(); e != nil && e != context.Canceled {
+			t.Error(e)
+		}
+	}()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	reply := make(chan []byte, 1)
+	cr := Setup()
+	cr.Handle(1, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+
+	c, err := Dial("127.0.0.1:11112", WithRouter(cr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, c.Send(1, []byte("hello")))
+	select {
+	case data := <-reply:
+		assert.Equal(t, []byte("echo: hello"), data)
+	case <-time.After(3 * time.Second):
+		t.Error("client receive reply timeout")
+	}
+}
+
+func TestDialRefused(t *testing.T) {
+	_, err := Dial("127.0.0.1:1")
+	assert.Error(t, err)
+}
+
+func TestClientCall(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(append([]byte("echo: "), ctx.RawData()...))
+	})
+	r.Handle(2, func(ctx *Context) {
+		time.Sleep(200 * time.Millisecond)
+		ctx.Write(ctx.RawData())
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11113), WithSequence(), WithRouter(r))
+	go func() {
+		if e := srv.On(); e != nil {
+			t.Error(e)
+		}
+	}()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	c, err := Dial("127.0.0.1:11113", WithSequence())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// 并发调用，每个响应都应回到对应的调用
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			req := []byte(fmt.Sprintf("call %d", i))
+			resp, e := c.Call(ctx, 1, req)
+			if assert.NoError(t, e) {
+				assert.Equal(t, append([]byte("echo: "), req...), resp)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 超过调用期限
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.Call(ctx, 2, []byte("slow"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientCallWithoutSequence(t *testing.T) {
+	c := &client{}
+	_, err := c.Call(context.Background(), 1, nil)
+	assert.ErrorIs(t, err, ErrSequenceRequired)
+}
@@ -0,0 +1,99 @@
+package orbit
+
+import (
+	"fmt"
+)
+
+// CloseReason 连接关闭原因
+type CloseReason int
+
+const (
+	// CloseEOF 对端关闭连接
+	CloseEOF CloseReason = iota + 1
+	// CloseReadError 读取数据出错
+	CloseReadError
+	// CloseWriteError 写出数据出错或超时
+	CloseWriteError
+	// CloseFrameTooLarge 收到的数据包超过允许的长度
+	CloseFrameTooLarge
+	// CloseIdleTimeout 读空闲超时、心跳超时或会话空闲超时
+	CloseIdleTimeout
+	// CloseShutdown 服务停止
+	CloseShutdown
+	// CloseKicked 被主动关闭，包括连接建立时被拒绝
+	CloseKicked
+	// CloseSlowConsumer 发送队列已满，对端接收过慢
+	CloseSlowConsumer
+)
+
+// String 关闭原因的描述
+func (r CloseReason) String() string {
+	switch r {
+	case CloseEOF:
+		return "eof"
+	case CloseReadError:
+		return "read error"
+	case CloseWriteError:
+		return "write error"
+	case CloseFrameTooLarge:
+		return "frame too large"
+	case CloseIdleTimeout:
+		return "idle timeout"
+	case CloseShutdown:
+		return "server shutdown"
+	case CloseKicked:
+		return "kicked"
+	case CloseSlowConsumer:
+		return "slow consumer"
+	}
+	return fmt.Sprintf("unknown(%d)", int(r))
+}
+
+// Error 关闭原因可以直接作为错误使用
+func (r CloseReason) Error() string {
+	return r.String()
+}
+
+// CloseError 连接关闭的原因及导致关闭的底层错误
+type CloseError struct {
+	Reason CloseReason
+	Err    error
+}
+
+// newCloseError 创建关闭原因
+func newCloseError(reason CloseReason, err error) *CloseError {
+	return &CloseError{Reason: reason, Err: err}
+}
+
+// Error 错误描述
+func (e *CloseError) Error() string {
+	if e.Err == nil {
+		return "connection closed: " + e.Reason.String()
+	}
+	return fmt.Sprintf("connection closed: %s: %v", e.Reason, e.Err)
+}
+
+// Unwrap 获取底层错误
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
+
+// Is 支持 errors.Is(err, CloseIdleTimeout) 判断关闭原因
+func (e *CloseError) Is(target error) bool {
+	r, ok := target.(CloseReason)
+	return ok && r == e.Reason
+}
+
+// reasonCloser 可以指定关闭原因的连接
+type reasonCloser interface {
+	closeWith(reason *CloseError)
+}
+
+// closeConn 按指定原因关闭连接，连接不支持时直接关闭
+func closeConn(conn Connection, reason *CloseError) {
+	if rc, ok := conn.(reasonCloser); ok {
+		rc.closeWith(reason)
+		return
+	}
+	conn.Close()
+}
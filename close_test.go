@@ -0,0 +1,234 @@
+package orbit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseError(t *testing.T) {
+	err := newCloseError(CloseReadError, io.ErrUnexpectedEOF)
+	assert.True(t, errors.Is(err, CloseReadError))
+	assert.False(t, errors.Is(err, CloseEOF))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.Equal(t, "connection closed: read error: unexpected EOF", err.Error())
+	assert.Equal(t, "connection closed: kicked", newCloseError(CloseKicked, nil).Error())
+
+	var ce *CloseError
+	assert.True(t, errors.As(error(err), &ce))
+	assert.Equal(t, CloseReadError, ce.Reason)
+}
+
+func TestOnConnect(t *testing.T) {
+	reject := errors.New("rejected")
+	disconnected := make(chan error, 1)
+	visible := make(chan bool, 1)
+	var srv Server
+	srv = New(
+		WithRouter(Setup()),
+		WithOnConnect(func(conn Connection) error {
+			// 回调通过前连接不可见，也收不到广播
+			_, err := srv.Manager().GetByID(conn.ID())
+			visible <- err == nil
+			srv.Manager().Broadcast(5, []byte("secret"))
+			return reject
+		}),
+		WithOnDisconnect(func(conn Connection, reason error) { disconnected <- reason }),
+	)
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	// 被拒绝的连接不会加入连接管理，也不会触发断开回调
+	assert.Equal(t, reject, srv.ServeConn(server))
+	assert.False(t, <-visible)
+	assert.Equal(t, 0, srv.Manager().Len())
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	select {
+	case reason := <-disconnected:
+		t.Errorf("unexpected disconnect: %v", reason)
+	default:
+	}
+}
+
+func TestOnDisconnect(t *testing.T) {
+	dp := NewDataPacket()
+	tests := []struct {
+		name   string
+		opts   []Option
+		peer   func(srv Server, client net.Conn)
+		reason CloseReason
+	}{
+		{
+			name: "eof",
+			peer: func(srv Server, client net.Conn) {
+				client.Close()
+			},
+			reason: CloseEOF,
+		},
+		{
+			name: "frame too large",
+			opts: []Option{WithMaxMessagePacketSize(4)},
+			peer: func(srv Server, client net.Conn) {
+				buff, _ := dp.Pack(NewMessagePacket(1, []byte("too large")))
+				client.Write(buff)
+			},
+			reason: CloseFrameTooLarge,
+		},
+		{
+			name:   "idle timeout",
+			opts:   []Option{WithReadIdleTimeout(50 * time.Millisecond)},
+			peer:   func(srv Server, client net.Conn) {},
+			reason: CloseIdleTimeout,
+		},
+		{
+			name: "kicked",
+			peer: func(srv Server, client net.Conn) {
+				buff, _ := dp.Pack(NewMessagePacket(2, nil))
+				client.Write(buff)
+			},
+			reason: CloseKicked,
+		},
+		{
+			name: "shutdown",
+			peer: func(srv Server, client net.Conn) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			},
+			reason: CloseShutdown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Setup()
+			r.Handle(2, func(ctx *Context) {
+				ctx.Connection().Close()
+			})
+
+			connected := make(chan Connection, 1)
+			disconnected := make(chan error, 1)
+			opts := append([]Option{
+				WithRouter(r),
+				WithOnConnect(func(conn Connection) error {
+					connected <- conn
+					return nil
+				}),
+				WithOnDisconnect(func(conn Connection, reason error) {
+					disconnected <- reason
+				}),
+			}, tt.opts...)
+			srv := New(opts...)
+			defer srv.Off()
+
+			server, client := net.Pipe()
+			defer client.Close()
+			go srv.ServeConn(server)
+			conn := <-connected
+
+			tt.peer(srv, client)
+			select {
+			case reason := <-disconnected:
+				assert.ErrorIs(t, reason, tt.reason)
+				_, err := srv.Manager().GetByID(conn.ID())
+				assert.Error(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("disconnect callback not called")
+			}
+		})
+	}
+}
+
+func TestOnConnectNotBlockAccept(t *testing.T) {
+	// 第一个连接的回调阻塞时仍然可以接入其他连接
+	release := make(chan struct{})
+	connected := make(chan uint64, 2)
+	var first int32
+	srv := New(
+		WithIP("127.0.0.1"),
+		WithPort(11128),
+		WithRouter(Setup()),
+		WithOnConnect(func(conn Connection) error {
+			if atomic.CompareAndSwapInt32(&first, 0, 1) {
+				<-release
+			}
+			connected <- conn.ID()
+			return nil
+		}),
+	)
+	go srv.On()
+	defer srv.Off()
+	defer close(release)
+	time.Sleep(100 * time.Millisecond)
+
+	slow, err := net.Dial("tcp", "127.0.0.1:11128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	fast, err := net.Dial("tcp", "127.0.0.1:11128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fast.Close()
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("slow connect callback blocks accept")
+	}
+}
+
+func TestMaxConnsPending(t *testing.T) {
+	// 等待建立的连接占用名额，同时接入的连接不会超过上限
+	release := make(chan struct{})
+	var entered int32
+	srv := New(
+		WithRouter(Setup()),
+		WithMaxConns(2),
+		WithOnConnect(func(conn Connection) error {
+			atomic.AddInt32(&entered, 1)
+			<-release
+			return nil
+		}),
+	)
+	defer srv.Off()
+
+	rejected := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		server, client := net.Pipe()
+		defer client.Close()
+		go func() {
+			if err := srv.ServeConn(server); err != nil {
+				rejected <- err
+			}
+		}()
+	}
+
+	for i := 0; i < 6; i++ {
+		select {
+		case err := <-rejected:
+			assert.ErrorIs(t, err, ErrTooManyConns)
+		case <-time.After(time.Second):
+			t.Fatal("connections over limit not rejected")
+		}
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&entered) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, srv.Manager().Len())
+
+	// 回调通过后加入连接管理
+	close(release)
+	assert.Eventually(t, func() bool { return srv.Manager().Len() == 2 }, time.Second, 10*time.Millisecond)
+}
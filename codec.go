@@ -0,0 +1,155 @@
+package orbit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FrameError 读取数据包的错误，Err 为 ErrTruncatedHead、ErrTruncatedBody 或 ErrMessageTooLarge，Cause 为导致错误的底层错误
+type FrameError struct {
+	Err   error
+	Cause error
+}
+
+// Error 错误描述
+func (e *FrameError) Error() string {
+	if e.Cause == nil {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Cause.Error()
+}
+
+// Unwrap 获取底层错误
+func (e *FrameError) Unwrap() error {
+	return e.Cause
+}
+
+// Is 支持 errors.Is(err, ErrTruncatedHead) 判断错误类型
+func (e *FrameError) Is(target error) bool {
+	return target == e.Err
+}
+
+// Decoder 从数据流中读取完整的消息，不能并发使用
+type Decoder struct {
+	dp      Packet
+	hd      HeadDecoder
+	sr      StreamReader
+	maxSize uint32
+
+	// 包头和消息在每次读取时复用
+	head []byte
+	msg  message
+
+	// 使用缓冲池存放消息内容
+	pool bool
+}
+
+// NewDecoder 创建解码器，maxSize 为消息内容的最大长度，为 0 时不限制
+func NewDecoder(dp Packet, maxSize uint32) *Decoder {
+	d := &Decoder{
+		dp:      dp,
+		maxSize: maxSize,
+		head:    make([]byte, dp.GetHeadLength()),
+	}
+	d.hd, _ = dp.(HeadDecoder)
+	d.sr, _ = dp.(StreamReader)
+
+	return d
+}
+
+// ReadMessage 读取一条完整的消息，返回的消息在下一次读取前有效，消息内容每次重新分配，
+// 数据流在消息边界处结束时返回 io.EOF
+func (d *Decoder) ReadMessage(r io.Reader) (Message, error) {
+	msg, _, err := d.read(r)
+	return msg, err
+}
+
+// read 读取一条完整的消息，启用缓冲池时同时返回存放消息内容的缓冲区，使用完成后需要回收
+func (d *Decoder) read(r io.Reader) (Message, *[]byte, error) {
+	// 数据包自行读取变长的包头和消息内容
+	if d.sr != nil {
+		msg, err := d.sr.ReadMessage(r, d.maxSize)
+		return msg, nil, err
+	}
+
+	// 读取消息的 head，没有读取到任何数据时直接返回底层错误
+	if n, err := io.ReadFull(r, d.head); err != nil {
+		if n == 0 {
+			return nil, nil, err
+		}
+		return nil, nil, &FrameError{Err: ErrTruncatedHead, Cause: err}
+	}
+
+	// 拆包，获取消息 id 和长度
+	var msg Message = &d.msg
+	var err error
+	if d.hd != nil {
+		err = d.hd.DecodeHead(d.head, msg, d.maxSize)
+	} else {
+		msg, err = d.dp.Unpack(d.head, d.maxSize)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMessageTooLarge) {
+			return nil, nil, &FrameError{Err: ErrMessageTooLarge}
+		}
+		return nil, nil, err
+	}
+
+	// 根据消息长度读取 data
+	var data []byte
+	var buff *[]byte
+	if msg.GetLength() > 0 {
+		if d.pool {
+			buff = getBuffer(int(msg.GetLength()))
+			data = *buff
+		} else {
+			data = make([]byte, msg.GetLength())
+		}
+		if _, err = io.ReadFull(r, data); err != nil {
+			putBuffer(buff)
+			return nil, nil, &FrameError{Err: ErrTruncatedBody, Cause: err}
+		}
+	}
+	msg.SetData(data)
+
+	return msg, buff, nil
+}
+
+// Encoder 将消息封包后写入数据流
+type Encoder struct {
+	dp   Packet
+	buff []byte
+}
+
+// NewEncoder 创建编码器
+func NewEncoder(dp Packet) *Encoder {
+	return &Encoder{dp: dp}
+}
+
+// WriteMessage 封包并写出一条消息，数据包支持追加封包时复用缓冲区，不能并发调用
+func (e *Encoder) WriteMessage(w io.Writer, msg Message) error {
+	var buff []byte
+	var err error
+	if pa, ok := e.dp.(PacketAppender); ok {
+		buff, err = pa.AppendPack(e.buff[:0], msg)
+		e.buff = buff
+	} else {
+		buff, err = e.dp.Pack(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("pack msg: %w", err)
+	}
+
+	_, err = w.Write(buff)
+	return err
+}
+
+// Encode 封包一条消息，返回的数据为新分配的内存，可以在写出前保留，可以并发调用
+func (e *Encoder) Encode(msg Message) ([]byte, error) {
+	buff, err := e.dp.Pack(msg)
+	if err != nil {
+		return nil, fmt.Errorf("pack msg: %w", err)
+	}
+	return buff, nil
+}
@@ -0,0 +1,160 @@
+package orbit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	for _, dp := range []Packet{NewDataPacket(), NewSeqDataPacket(), &bigEndianPacket{}} {
+		var stream bytes.Buffer
+		enc := NewEncoder(dp)
+		assert.NoError(t, enc.WriteMessage(&stream, NewMessagePacket(1, []byte("hello"))))
+		assert.NoError(t, enc.WriteMessage(&stream, NewMessagePacket(2, nil)))
+
+		dec := NewDecoder(dp, 4096)
+		msg, err := dec.ReadMessage(&stream)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(1), msg.GetProtocol())
+		assert.Equal(t, "hello", string(msg.GetData()))
+
+		msg, err = dec.ReadMessage(&stream)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(2), msg.GetProtocol())
+		assert.Empty(t, msg.GetData())
+
+		// 数据流在消息边界处结束
+		_, err = dec.ReadMessage(&stream)
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	dp := NewDataPacket()
+	frame, _ := dp.Pack(NewMessagePacket(1, []byte("hello")))
+
+	_, err := NewDecoder(dp, 4096).ReadMessage(bytes.NewReader(frame[:3]))
+	assert.True(t, errors.Is(err, ErrTruncatedHead))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	_, err = NewDecoder(dp, 4096).ReadMessage(bytes.NewReader(frame[:10]))
+	assert.True(t, errors.Is(err, ErrTruncatedBody))
+	assert.False(t, errors.Is(err, ErrTruncatedHead))
+
+	_, err = NewDecoder(dp, 4).ReadMessage(bytes.NewReader(frame))
+	assert.True(t, errors.Is(err, ErrMessageTooLarge))
+
+	var fe *FrameError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, ErrMessageTooLarge, fe.Err)
+}
+
+// varintPacket 使用 varint 编码长度和协议号的变长包头
+type varintPacket struct{}
+
+func (pk *varintPacket) GetHeadLength() uint32 { return 0 }
+
+func (pk *varintPacket) Pack(msg Message) ([]byte, error) {
+	buff := make([]byte, 2*binary.MaxVarintLen32, 2*binary.MaxVarintLen32+len(msg.GetData()))
+	n := binary.PutUvarint(buff, uint64(msg.GetLength()))
+	n += binary.PutUvarint(buff[n:], uint64(msg.GetProtocol()))
+	return append(buff[:n], msg.GetData()...), nil
+}
+
+func (pk *varintPacket) Unpack(data []byte, maxSize uint32) (Message, error) {
+	return nil, errors.New("varint packet must be read from stream")
+}
+
+func (pk *varintPacket) ReadMessage(r io.Reader, maxSize uint32) (Message, error) {
+	br := &byteReader{r: r}
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && length > uint64(maxSize) {
+		return nil, ErrMessageTooLarge
+	}
+	protocol, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, &FrameError{Err: ErrTruncatedHead, Cause: err}
+	}
+
+	data := make([]byte, length)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, &FrameError{Err: ErrTruncatedBody, Cause: err}
+	}
+	return NewMessagePacket(uint32(protocol), data), nil
+}
+
+// byteReader 逐字节读取数据流，不预读后续消息
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(br.r, br.b[:]); err != nil {
+		return 0, err
+	}
+	return br.b[0], nil
+}
+
+func TestDecoderStreamReader(t *testing.T) {
+	dp := &varintPacket{}
+	var stream bytes.Buffer
+	enc := NewEncoder(dp)
+	assert.NoError(t, enc.WriteMessage(&stream, NewMessagePacket(300, []byte("hello"))))
+	assert.NoError(t, enc.WriteMessage(&stream, NewMessagePacket(2, nil)))
+
+	// 变长包头由数据包自行读取
+	dec := NewDecoder(dp, 4096)
+	msg, err := dec.ReadMessage(&stream)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(300), msg.GetProtocol())
+		assert.Equal(t, "hello", string(msg.GetData()))
+	}
+	msg, err = dec.ReadMessage(&stream)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(2), msg.GetProtocol())
+		assert.Empty(t, msg.GetData())
+	}
+	_, err = dec.ReadMessage(&stream)
+	assert.ErrorIs(t, err, io.EOF)
+
+	frame, _ := dp.Pack(NewMessagePacket(1, []byte("hello")))
+	_, err = NewDecoder(dp, 4).ReadMessage(bytes.NewReader(frame))
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+}
+
+func TestStreamReaderServeConn(t *testing.T) {
+	r := Setup()
+	r.Handle(300, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+	srv := New(WithPacket(&varintPacket{}), WithRouter(r))
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go srv.ServeConn(server)
+
+	// 连接按变长包头读写
+	dp := &varintPacket{}
+	send, _ := dp.Pack(NewMessagePacket(300, []byte("varint")))
+	client.SetDeadline(time.Now().Add(time.Second))
+	if _, err := client.Write(send); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := NewDecoder(dp, 0).ReadMessage(client)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(300), msg.GetProtocol())
+		assert.Equal(t, "varint", string(msg.GetData()))
+	}
+}
@@ -2,62 +2,166 @@ package orbit
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// connID 已分配的最大连接 id
+var connID uint64
+
+// nextConnID 分配连接 id，进程内单调递增且不重复
+func nextConnID() uint64 {
+	return atomic.AddUint64(&connID, 1)
+}
+
 // Connection 连接接口
 type Connection interface {
+	ID() uint64
 	Handle()
 	Close()
+	Shutdown(ctx context.Context) error
 	Send(protocol uint32, data []byte) error
+	SendContext(ctx context.Context, protocol uint32, data []byte) error
+	SendMessage(msg Message) error
+	SendQueueLen() int
 	RemoteAddr() string
+	PeerCertificates() []*x509.Certificate
+	Manager() Manager
+
+	Set(key string, value interface{})
+	Get(key string) (interface{}, bool)
+	Delete(key string)
 }
 
 // connection 连接结构体
 type connection struct {
-	conn    *net.TCPConn
+	id      uint64
+	conn    net.Conn
 	manager Manager
 	worker  Worker
+	properties
+
+	dec        *Decoder
+	enc        *Encoder
+	serializer Serializer
+	msgCh      chan []byte
 
-	size  uint32
-	msgCh chan []byte
+	// 发送队列相关
+	sendPolicy  SendPolicy
+	sendTimeout time.Duration
+	writeBatch  int
+	bufs        net.Buffers
+	writev      bool
+	wbuf        []byte
+
+	// 超时与心跳相关
+	readIdle  time.Duration
+	writeIdle time.Duration
+	heartbeat heartbeat
+	active    int32
 
 	ctx    context.Context
 	cancel context.CancelFunc
-	close  bool
+	close  int32
+
+	// 关闭原因，只记录第一次关闭的原因
+	reason       *CloseError
+	reasonOnce   sync.Once
+	onConnect    func(conn Connection) error
+	onDisconnect func(conn Connection, reason error)
+	state        int32
+	rejected     error
+
+	noRoute uint32
+
+	// 优雅关闭相关
+	draining  int32
+	drainOnce sync.Once
+	drained   chan struct{}
+	drainErr  error
+	tasks     sync.WaitGroup
+	flush     chan struct{}
+	readDone  chan struct{}
+	writeDone chan struct{}
 }
 
-// newConnection 创建连接
-func newConnection(conn *net.TCPConn, manager Manager, worker Worker, size uint32) Connection {
+// 连接建立的状态
+const (
+	statePending int32 = iota
+	stateConnected
+	stateClosed
+)
+
+// newConnection 创建连接，连接在读协程中完成建立后才加入连接管理
+func newConnection(conn net.Conn, manager Manager, worker Worker, o options) *connection {
+	dp := newPacket(o)
 	c := &connection{
+		id:      nextConnID(),
 		conn:    conn,
 		manager: manager,
 		worker:  worker,
 
-		size:  size,
-		msgCh: make(chan []byte, 1024),
+		dec:        NewDecoder(dp, o.packet),
+		enc:        NewEncoder(dp),
+		serializer: o.serializer,
+		msgCh:      make(chan []byte, o.sendQueue),
+
+		sendPolicy:  o.sendPolicy,
+		sendTimeout: o.sendTimeout,
+		writeBatch:  o.writeBatch,
+		writev:      vectored(conn),
+
+		readIdle:  o.readIdle,
+		writeIdle: o.writeTimeout,
+		heartbeat: o.heartbeat,
 
-		close: false,
+		onConnect:    o.onConnect,
+		onDisconnect: o.onDisconnect,
+
+		flush:     make(chan struct{}),
+		drained:   make(chan struct{}),
+		readDone:  make(chan struct{}),
+		writeDone: make(chan struct{}),
 	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.dec.pool = o.bufferPool
 
-	c.manager.Add(c)
+	// 没有连接建立的回调时直接完成建立
+	if c.onConnect == nil {
+		c.state = stateConnected
+	}
 
 	return c
 }
 
+// ID 获取连接 id
+func (c *connection) ID() uint64 {
+	return c.id
+}
+
+// Manager 获取连接所属的连接管理
+func (c *connection) Manager() Manager {
+	return c.manager
+}
+
 // Handle 处理连接
 func (c *connection) Handle() {
-	c.ctx, c.cancel = context.WithCancel(context.Background())
-
 	// 开启读取客户端数据流的 Goroutine
 	go c.readProcessor()
 	// 开启返回数据给客户端的 Goroutine
 	go c.writeProcessor()
+	// 开启心跳检测的 Goroutine
+	if c.heartbeat.interval > 0 {
+		go c.heartbeatProcessor()
+	}
 
 	// 阻塞等待上下文的取消信号
 	select {
@@ -71,44 +175,100 @@ func (c *connection) Handle() {
 func (c *connection) readProcessor() {
 	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s reader goroutine is running", c.RemoteAddr()))
 	defer log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s reader exit", c.RemoteAddr()))
-	defer c.Close()
+	var reason *CloseError
+	defer func() {
+		close(c.readDone)
+		// 优雅关闭时由 Shutdown 负责关闭连接
+		if atomic.LoadInt32(&c.draining) == 0 {
+			if reason == nil {
+				reason = newCloseError(CloseKicked, nil)
+			}
+			c.closeWith(reason)
+		}
+	}()
+
+	// TLS 连接先完成握手，确保处理消息时可以获取对端证书
+	if tc, ok := c.conn.(*tls.Conn); ok {
+		if !c.extendReadDeadline() {
+			return
+		}
+		if err := tc.HandshakeContext(c.ctx); err != nil {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s tls handshake err: %e", c.RemoteAddr(), err))
+			reason = readCloseError(err)
+			return
+		}
+	}
+
+	// 握手完成后执行连接建立的回调，可以获取对端证书，返回错误时拒绝连接
+	if c.onConnect != nil {
+		if err := c.onConnect(c); err != nil {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s rejected: %e", c.RemoteAddr(), err))
+			c.rejected = err
+			reason = newCloseError(CloseKicked, err)
+			return
+		}
+
+		// 回调执行期间连接已关闭，由这里触发断开回调
+		if !atomic.CompareAndSwapInt32(&c.state, statePending, stateConnected) {
+			if c.onDisconnect != nil {
+				c.onDisconnect(c, c.reason)
+			}
+			return
+		}
+	}
+
+	// 连接建立完成后才加入连接管理，之前不会被遍历、查找或收到广播
+	c.manager.Add(c)
+	if c.ctx.Err() != nil {
+		// 加入前连接已关闭，关闭处理可能已经执行过删除
+		c.manager.Del(c)
+		return
+	}
 
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
 		default:
-			// 读取客户端消息的 head
-			dp := NewDataPacket()
-			head := make([]byte, dp.GetHeadLength())
-			if _, err := io.ReadFull(c.conn, head); err != nil {
-				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s read msg head err: %e", c.RemoteAddr(), err))
+			if !c.extendReadDeadline() {
 				return
 			}
 
-			// 拆包，获取消息 id 和长度
-			msg, err := dp.Unpack(head, c.size)
+			// 读取一条完整的消息
+			msg, buff, err := c.dec.read(c.conn)
 			if err != nil {
-				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s unpack msg err: %e", c.RemoteAddr(), err))
+				switch {
+				case isTimeout(err) && c.readIdle > 0 && atomic.LoadInt32(&c.draining) == 0:
+					log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s read idle timeout", c.RemoteAddr()))
+					reason = newCloseError(CloseIdleTimeout, err)
+				case errors.Is(err, ErrMessageTooLarge):
+					log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s unpack msg err: %e", c.RemoteAddr(), err))
+					reason = newCloseError(CloseFrameTooLarge, err)
+				default:
+					log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s read msg err: %e", c.RemoteAddr(), err))
+					reason = readCloseError(err)
+				}
 				return
 			}
+			atomic.StoreInt32(&c.active, 1)
 
-			// 根据消息长度读取 data
-			var data []byte
-			if msg.GetLength() > 0 {
-				data = make([]byte, msg.GetLength())
-				if _, e := io.ReadFull(c.conn, data); e != nil {
-					log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s read data head err: %e", c.RemoteAddr(), err))
-					return
-				}
+			// 心跳消息直接处理，不进入工作池
+			if c.handleHeartbeat(msg) {
+				putBuffer(buff)
+				continue
 			}
-			msg.SetData(data)
 
 			// 将消息交给工作池的任务队列中进行处理处理
+			c.tasks.Add(1)
 			c.worker.JoinTaskQueue(&Context{
 				protocol: msg.GetProtocol(),
+				seq:      msg.GetSeq(),
 				data:     msg.GetData(),
+				buff:     buff,
 				conn:     c,
+				tasks:    &c.tasks,
+
+				serializer: c.serializer,
 			})
 		}
 	}
@@ -118,69 +278,276 @@ func (c *connection) readProcessor() {
 func (c *connection) writeProcessor() {
 	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s writer goroutine is running", c.RemoteAddr()))
 	defer log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s writer exit", c.RemoteAddr()))
+	defer close(c.writeDone)
 
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
-		case data, ok := <-c.msgCh:
-			if !ok {
-				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s msg buff chan is closed", c.RemoteAddr()))
-				return
+		case <-c.flush:
+			// 将缓冲区中剩余的数据全部写出后退出
+			for {
+				select {
+				case data := <-c.msgCh:
+					if !c.write(c.batch(data)) {
+						return
+					}
+				default:
+					return
+				}
 			}
-			if _, err := c.conn.Write(data); err != nil {
-				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s write buff err: %e", c.RemoteAddr(), err))
+		case data := <-c.msgCh:
+			if !c.write(c.batch(data)) {
 				return
 			}
 		}
 	}
 }
 
+// batch 将发送队列中已经在等待的数据与 first 合并，达到 writeBatch 字节后停止合并
+func (c *connection) batch(first []byte) net.Buffers {
+	c.bufs = append(c.bufs[:0], first)
+	for size := len(first); size < c.writeBatch; {
+		select {
+		case data := <-c.msgCh:
+			c.bufs = append(c.bufs, data)
+			size += len(data)
+		default:
+			return c.bufs
+		}
+	}
+	return c.bufs
+}
+
+// vectored 连接是否支持向量写入，net.Buffers 只对 TCP 和 unix 连接使用 writev
+func vectored(conn net.Conn) bool {
+	switch conn.(type) {
+	case *net.TCPConn, *net.UnixConn:
+		return true
+	}
+	return false
+}
+
+// write 写出数据，TCP 和 unix 连接使用一次向量写入，TLS、WebSocket 等连接先复制到复用的缓冲区再一次写出，
+// 设置了写超时时对端长时间不接收数据会导致连接关闭
+func (c *connection) write(bufs net.Buffers) bool {
+	if c.writeIdle > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeIdle)); err != nil {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s set write deadline err: %e", c.RemoteAddr(), err))
+			c.closeWith(newCloseError(CloseWriteError, err))
+			return false
+		}
+	}
+
+	var err error
+	if c.writev || len(bufs) == 1 {
+		_, err = bufs.WriteTo(c.conn)
+	} else {
+		// 合并为一次写出，TLS 连接只产生一个记录，WebSocket 连接只产生一条消息
+		c.wbuf = c.wbuf[:0]
+		for _, b := range bufs {
+			c.wbuf = append(c.wbuf, b...)
+		}
+		_, err = c.conn.Write(c.wbuf)
+	}
+	if err != nil {
+		if isTimeout(err) {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s write timeout", c.RemoteAddr()))
+		} else {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s write buff err: %e", c.RemoteAddr(), err))
+		}
+		c.closeWith(newCloseError(CloseWriteError, err))
+		return false
+	}
+
+	return true
+}
+
+// extendReadDeadline 设置了读空闲超时时延长读取期限，优雅关闭时不再读取
+func (c *connection) extendReadDeadline() bool {
+	if c.readIdle <= 0 {
+		return true
+	}
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.readIdle)); err != nil {
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s set read deadline err: %e", c.RemoteAddr(), err))
+		return false
+	}
+
+	// 先设置期限再检查状态，避免覆盖 Shutdown 设置的期限
+	return atomic.LoadInt32(&c.draining) == 0
+}
+
+// Send 发送数据
 func (c *connection) Send(protocol uint32, data []byte) error {
-	if c.close {
-		return errors.New("connection closed when send buff msg")
+	return c.SendMessage(NewMessagePacket(protocol, data))
+}
+
+// SendContext 发送数据，阻塞策略下发送队列已满时等待直到 ctx 结束
+func (c *connection) SendContext(ctx context.Context, protocol uint32, data []byte) error {
+	buff, err := c.pack(NewMessagePacket(protocol, data))
+	if err != nil {
+		return err
+	}
+
+	return c.enqueue(ctx, buff)
+}
+
+// SendMessage 发送消息包
+func (c *connection) SendMessage(msg Message) error {
+	buff, err := c.pack(msg)
+	if err != nil {
+		return err
 	}
 
-	// 将数据封包
-	dp := NewDataPacket()
-	msg, err := dp.Pack(NewMessagePacket(protocol, data))
+	return c.sendRaw(buff)
+}
+
+// pack 将数据封包
+func (c *connection) pack(msg Message) ([]byte, error) {
+	if c.closed() {
+		return nil, ErrConnClosed
+	}
+
+	buff, err := c.enc.Encode(msg)
 	if err != nil {
 		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s pack msg err: %e", c.RemoteAddr(), err))
-		return errors.New(fmt.Sprintf("pack error msg"))
+		return nil, err
 	}
 
-	// 如果管道关闭做超时处理
-	timeout := time.NewTimer(5 * time.Millisecond)
-	defer timeout.Stop()
-	select {
-	case <-timeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgCh <- msg:
-		return nil
+	return buff, nil
+}
+
+// sendRaw 发送已封包的数据，阻塞策略下最多等待 sendTimeout，buff 可能被多个连接共享，不能修改
+func (c *connection) sendRaw(buff []byte) error {
+	if c.sendTimeout <= 0 {
+		return c.enqueue(context.Background(), buff)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.sendTimeout)
+	defer cancel()
+	if err := c.enqueue(ctx, buff); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrSendTimeout
+		}
+		return err
+	}
+	return nil
 }
 
 // Close 关闭连接
 func (c *connection) Close() {
+	c.closeWith(newCloseError(CloseKicked, nil))
+}
+
+// closeWith 按指定原因关闭连接，已关闭时保留第一次的原因
+func (c *connection) closeWith(reason *CloseError) {
+	c.reasonOnce.Do(func() {
+		c.reason = reason
+	})
 	c.cancel()
 }
 
-// finalizer 连接关闭后的处理
+// Shutdown 优雅关闭连接，停止读取，等待已读取的消息处理完成并将待发送的数据写出后关闭
+func (c *connection) Shutdown(ctx context.Context) error {
+	defer c.closeWith(newCloseError(CloseShutdown, nil))
+
+	// 连接已关闭
+	select {
+	case <-c.ctx.Done():
+		return nil
+	default:
+	}
+
+	// 多次调用时只执行一次排空，所有调用方等待同一个结果
+	c.drainOnce.Do(func() {
+		go c.drain()
+	})
+	select {
+	case <-c.drained:
+		return c.drainErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain 停止读取，等待已读取的消息处理完成并将待发送的数据写出，完成后关闭 drained
+func (c *connection) drain() {
+	defer close(c.drained)
+
+	// 停止读取，唤醒阻塞在读取上的协程
+	atomic.StoreInt32(&c.draining, 1)
+	if err := c.conn.SetReadDeadline(time.Now()); err != nil {
+		c.drainErr = err
+		return
+	}
+	select {
+	case <-c.readDone:
+	case <-c.ctx.Done():
+	}
+
+	// 等待工作池处理完当前连接的任务
+	c.tasks.Wait()
+
+	// 将待发送的数据写出
+	close(c.flush)
+	select {
+	case <-c.writeDone:
+	case <-c.ctx.Done():
+	}
+}
+
+// incNoRoute 请求未注册协议的次数加一
+func (c *connection) incNoRoute() uint32 {
+	return atomic.AddUint32(&c.noRoute, 1)
+}
+
+// closed 连接是否已关闭
+func (c *connection) closed() bool {
+	return atomic.LoadInt32(&c.close) == 1
+}
+
+// finalizer 连接关闭后的处理，发送队列不关闭，写协程和发送方通过 ctx 感知连接关闭
 func (c *connection) finalizer() {
-	if c.close {
+	if !atomic.CompareAndSwapInt32(&c.close, 0, 1) {
 		return
 	}
 	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s connection is closeing", c.RemoteAddr()))
 
 	c.conn.Close()
 	c.manager.Del(c)
-	close(c.msgCh)
 
-	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s connection closed", c.RemoteAddr()))
-	c.close = true
+	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s connection closed: %s", c.RemoteAddr(), c.reason.Reason))
+
+	// 未完成建立的连接不触发断开回调
+	if atomic.SwapInt32(&c.state, stateClosed) == stateConnected && c.onDisconnect != nil {
+		c.onDisconnect(c, c.reason)
+	}
 }
 
 // RemoteAddr 获取远程客户端地址
 func (c *connection) RemoteAddr() string {
 	return c.conn.RemoteAddr().String()
 }
+
+// PeerCertificates 获取 TLS 连接对端的证书链，非 TLS 连接或对端未提供证书时为空
+func (c *connection) PeerCertificates() []*x509.Certificate {
+	if tc, ok := c.conn.(*tls.Conn); ok {
+		return tc.ConnectionState().PeerCertificates
+	}
+	return nil
+}
+
+// readCloseError 根据读取错误判断关闭原因
+func readCloseError(err error) *CloseError {
+	if errors.Is(err, io.EOF) {
+		return newCloseError(CloseEOF, err)
+	}
+	return newCloseError(CloseReadError, err)
+}
+
+// isTimeout 是否为超时错误
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -1,10 +1,62 @@
 package orbit
 
+import (
+	"crypto/x509"
+	"math"
+	"sync"
+)
+
+// abortIndex 中止执行时的索引
+const abortIndex int8 = math.MaxInt8 >> 1
+
 // Context 结构体
 type Context struct {
 	protocol uint32
+	seq      uint32
 	data     []byte
+	buff     *[]byte
 	conn     Connection
+	tasks    *sync.WaitGroup
+
+	serializer Serializer
+
+	handlers HandlersChain
+	index    int8
+}
+
+// ConnID 获取连接 id
+func (ctx *Context) ConnID() uint64 {
+	return ctx.conn.ID()
+}
+
+// Connection 获取当前连接
+func (ctx *Context) Connection() Connection {
+	return ctx.conn
+}
+
+// Manager 获取连接所属的连接管理
+func (ctx *Context) Manager() Manager {
+	return ctx.conn.Manager()
+}
+
+// Rooms 获取房间
+func (ctx *Context) Rooms() Rooms {
+	return ctx.conn.Manager().Rooms()
+}
+
+// Set 设置连接属性，属性在连接的整个生命周期内有效
+func (ctx *Context) Set(key string, value interface{}) {
+	ctx.conn.Set(key, value)
+}
+
+// Get 获取连接属性
+func (ctx *Context) Get(key string) (interface{}, bool) {
+	return ctx.conn.Get(key)
+}
+
+// Delete 删除连接属性
+func (ctx *Context) Delete(key string) {
+	ctx.conn.Delete(key)
 }
 
 // RemoteAddr 获取客户端地址
@@ -12,17 +64,83 @@ func (ctx *Context) RemoteAddr() string {
 	return ctx.conn.RemoteAddr()
 }
 
+// PeerCertificates 获取 TLS 连接对端的证书链
+func (ctx *Context) PeerCertificates() []*x509.Certificate {
+	return ctx.conn.PeerCertificates()
+}
+
+// PeerSubject 获取 TLS 连接对端证书的主题，没有证书时为空字符串
+func (ctx *Context) PeerSubject() string {
+	certs := ctx.PeerCertificates()
+	if len(certs) == 0 {
+		return ""
+	}
+	return certs[0].Subject.String()
+}
+
 // Protocol 获取当前服务所属模块
 func (ctx *Context) Protocol() uint32 {
 	return ctx.protocol
 }
 
+// Seq 获取请求序列号，未使用带序列号的数据包时为 0
+func (ctx *Context) Seq() uint32 {
+	return ctx.seq
+}
+
 // RawData 获取未处理过的请求数据
 func (ctx *Context) RawData() []byte {
 	return ctx.data
 }
 
-// Write 返回数据
+// Bind 按序列化方式解码请求数据
+func (ctx *Context) Bind(v interface{}) error {
+	return ctx.serializer.Unmarshal(ctx.data, v)
+}
+
+// Reply 按序列化方式编码后返回数据
+func (ctx *Context) Reply(v interface{}) error {
+	b, err := ctx.serializer.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.Write(b)
+}
+
+// Next 执行链中剩余的处理方法，只能在中间件中调用
+func (ctx *Context) Next() {
+	ctx.index++
+	for ctx.index < int8(len(ctx.handlers)) {
+		ctx.handlers[ctx.index](ctx)
+		ctx.index++
+	}
+}
+
+// Abort 中止执行链中剩余的处理方法，不影响当前方法的执行
+func (ctx *Context) Abort() {
+	ctx.index = abortIndex
+}
+
+// IsAborted 是否已中止
+func (ctx *Context) IsAborted() bool {
+	return ctx.index >= abortIndex
+}
+
+// Write 返回数据，响应会携带请求的序列号
 func (ctx *Context) Write(b []byte) error {
-	return ctx.conn.Send(ctx.protocol, b)
+	msg := NewMessagePacket(ctx.protocol, b)
+	msg.SetSeq(ctx.seq)
+	return ctx.conn.SendMessage(msg)
+}
+
+// done 任务处理完成，回收请求数据使用的缓冲区
+func (ctx *Context) done() {
+	if ctx.buff != nil {
+		putBuffer(ctx.buff)
+		ctx.buff = nil
+		ctx.data = nil
+	}
+	if ctx.tasks != nil {
+		ctx.tasks.Done()
+	}
 }
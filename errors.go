@@ -0,0 +1,31 @@
+package orbit
+
+import (
+	"errors"
+)
+
+var (
+	// ErrConnClosed 连接已关闭
+	ErrConnClosed = errors.New("connection closed")
+	// ErrConnNotFound 连接管理中没有对应的连接
+	ErrConnNotFound = errors.New("connection not found")
+	// ErrMessageTooLarge 消息长度超过允许值
+	ErrMessageTooLarge = errors.New("received too large message")
+	// ErrSendTimeout 等待发送队列超时
+	ErrSendTimeout = errors.New("send buff msg timeout")
+	// ErrQueueFull 发送队列已满
+	ErrQueueFull = errors.New("send queue is full")
+	// ErrRemote 对端处理请求出错
+	ErrRemote = errors.New("remote handle err")
+	// ErrNoRoute 对端没有注册请求的协议
+	ErrNoRoute = errors.New("protocol not found")
+	// ErrSequenceRequired 请求响应需要带序列号的数据包
+	ErrSequenceRequired = errors.New("call requires sequence packet, dial with WithSequence")
+	// ErrTooManyConns 连接数量达到上限，新的连接被拒绝
+	ErrTooManyConns = errors.New("too many connections")
+
+	// ErrTruncatedHead 数据流在读取包头的过程中结束
+	ErrTruncatedHead = errors.New("truncated message head")
+	// ErrTruncatedBody 数据流在读取消息内容的过程中结束
+	ErrTruncatedBody = errors.New("truncated message body")
+)
@@ -0,0 +1,72 @@
+package orbit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failPacket 封包总是失败的数据包
+type failPacket struct {
+	Packet
+}
+
+var errPack = errors.New("pack failed")
+
+func (pk *failPacket) Pack(msg Message) ([]byte, error) {
+	return nil, errPack
+}
+
+func TestErrors(t *testing.T) {
+	m := newManager(options{})
+	_, err := m.GetByID(0)
+	assert.ErrorIs(t, err, ErrConnNotFound)
+	_, err = m.Get("127.0.0.1:1")
+	assert.ErrorIs(t, err, ErrConnNotFound)
+	_, err = m.GetByUser("nobody")
+	assert.ErrorIs(t, err, ErrConnNotFound)
+	failed, err := m.Multicast([]uint64{0}, 1, nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, failed[0], ErrConnNotFound)
+
+	// 封包失败时保留原因
+	_, err = newManager(options{dp: &failPacket{NewDataPacket()}}).Broadcast(1, nil)
+	assert.ErrorIs(t, err, errPack)
+
+	c, _ := newQueueConn(t, SendBlock)
+	c.enc = NewEncoder(&failPacket{NewDataPacket()})
+	assert.ErrorIs(t, c.Send(1, nil), errPack)
+	assert.ErrorIs(t, c.enc.WriteMessage(nil, NewMessagePacket(1, nil)), errPack)
+
+	_, err = NewDataPacket().Unpack([]byte{1, 2}, 0)
+	assert.ErrorIs(t, err, ErrTruncatedHead)
+}
+
+func TestSendErrors(t *testing.T) {
+	c, _ := newQueueConn(t, SendBlock)
+	assert.NoError(t, c.Send(1, nil))
+	assert.NoError(t, c.Send(2, nil))
+	assert.ErrorIs(t, c.Send(3, nil), ErrSendTimeout)
+
+	// SendContext 返回 ctx 的错误
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.SendContext(ctx, 3, nil), context.DeadlineExceeded)
+
+	d, _ := newQueueConn(t, SendDropNewest)
+	assert.NoError(t, d.Send(1, nil))
+	assert.NoError(t, d.Send(2, nil))
+	assert.ErrorIs(t, d.Send(3, nil), ErrQueueFull)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	o := options{sendQueue: 1}
+	closed := newConnection(server, newManager(o), nil, o)
+	closed.Close()
+	closed.Handle()
+	assert.ErrorIs(t, closed.Send(1, nil), ErrConnClosed)
+}
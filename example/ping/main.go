@@ -1,10 +1,7 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"io"
-	"net"
 	"orbit"
 	"time"
 )
@@ -37,79 +34,46 @@ func main() {
 
 func Client0() {
 	time.Sleep(3 * time.Second)
-	conn, err := net.Dial("tcp", "127.0.0.1:4399")
-	if err != nil {
-		panic(err)
-	}
+	c := dial()
 
-	read(conn)
-	//conn.Close()
+	ping(c)
+	//c.Close()
 }
 
 func Client1() {
 	time.Sleep(3 * time.Second)
-	conn, err := net.Dial("tcp", "127.0.0.1:4399")
-	if err != nil {
-		panic(err)
-	}
+	c := dial()
 
-	read(conn)
-	conn.Close()
+	ping(c)
+	c.Close()
 }
 
 func Client2() {
 	time.Sleep(3 * time.Second)
-	conn, err := net.Dial("tcp", "127.0.0.1:4399")
-	if err != nil {
-		panic(err)
-	}
+	c := dial()
 
-	read(conn)
-	conn.Close()
+	ping(c)
+	c.Close()
 }
 
-func read(conn net.Conn) {
-	i := 0
-	for {
-		dp := orbit.NewDataPacket()
-		send, _ := dp.Pack(orbit.NewMessagePacket(1, []byte("ping")))
-		_, err := conn.Write(send)
-		if err != nil {
-			panic(err)
-		}
+func dial() orbit.Client {
+	r := orbit.Setup()
+	r.Handle(1, func(ctx *orbit.Context) {
+		fmt.Printf("[ CLIENT ] receive msg form server: protocol = %d, data = %s\n", ctx.Protocol(), ctx.RawData())
+	})
 
-		head := make([]byte, dp.GetHeadLength())
-		_, err = io.ReadFull(conn, head)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return
-			}
-			panic(err)
-		}
+	c, err := orbit.Dial("127.0.0.1:4399", orbit.WithRouter(r))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
 
-		receive, err := dp.Unpack(head, 4096)
-		if err != nil {
+func ping(c orbit.Client) {
+	for i := 0; i < 3; i++ {
+		if err := c.Send(1, []byte("ping")); err != nil {
 			panic(err)
 		}
-
-		var data []byte
-		if receive.GetLength() > 0 {
-			data = make([]byte, receive.GetLength())
-			_, e := io.ReadFull(conn, data)
-			if e != nil {
-				if errors.Is(err, io.EOF) {
-					return
-				}
-				panic(e)
-			}
-		}
-		fmt.Printf("[ CLIENT ] receive msg form server: protocol = %d, data = %s\n", receive.GetProtocol(), string(data))
-
-		if i >= 2 {
-			break
-		}
-
 		time.Sleep(3 * time.Second)
-		i++
 	}
 }
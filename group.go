@@ -0,0 +1,54 @@
+package orbit
+
+import (
+	"fmt"
+	"log"
+)
+
+// group 路由分组结构体
+type group struct {
+	root       *router
+	start      uint32
+	end        uint32
+	middleware HandlersChain
+}
+
+// newGroup 创建路由分组，分组区间必须在父级区间 [min, max] 之内
+func newGroup(root *router, min, max, start, end uint32, middleware HandlersChain) *group {
+	if start > end {
+		panic(fmt.Sprintf("invalid group range: [%d, %d]", start, end))
+	}
+	if start < min || end > max {
+		panic(fmt.Sprintf("group range [%d, %d] out of parent range [%d, %d]", start, end, min, max))
+	}
+
+	log.Println(fmt.Sprintf("[ ROUTER ] add group [%d, %d]", start, end))
+	return &group{
+		root:       root,
+		start:      start,
+		end:        end,
+		middleware: append(HandlersChain{}, middleware...),
+	}
+}
+
+// Use 添加分组中间件，只对之后注册的协议生效
+func (g *group) Use(middleware ...HandlerFunc) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Handle 在分组内添加处理句柄，协议必须在分组区间之内
+func (g *group) Handle(protocol uint32, handlers ...HandlerFunc) {
+	if protocol < g.start || protocol > g.end {
+		panic(fmt.Sprintf("protocol %d out of group range [%d, %d]", protocol, g.start, g.end))
+	}
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("protocol %d has no handler", protocol))
+	}
+
+	g.root.add(protocol, g.root.combine(g.middleware, handlers))
+}
+
+// Group 创建嵌套的子分组，继承当前分组的中间件
+func (g *group) Group(start, end uint32, middleware ...HandlerFunc) RouterGroup {
+	return newGroup(g.root, g.start, g.end, start, end, append(append(HandlersChain{}, g.middleware...), middleware...))
+}
@@ -0,0 +1,55 @@
+package orbit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	r.Use(func(ctx *Context) {
+		trace = append(trace, "global")
+	})
+
+	login := r.Group(1000, 1999, func(ctx *Context) {
+		trace = append(trace, "login")
+	})
+	login.Handle(1001, func(ctx *Context) {
+		trace = append(trace, "1001")
+	})
+
+	battle := r.Group(2000, 2999)
+	battle.Use(func(ctx *Context) {
+		trace = append(trace, "battle")
+	})
+	skill := battle.Group(2100, 2199, func(ctx *Context) {
+		trace = append(trace, "skill")
+	})
+	skill.Handle(2101, func(ctx *Context) {
+		trace = append(trace, "2101")
+	})
+
+	r.exec(&Context{protocol: 1001})
+	assert.Equal(t, []string{"global", "login", "1001"}, trace)
+
+	trace = nil
+	r.exec(&Context{protocol: 2101})
+	assert.Equal(t, []string{"global", "battle", "skill", "2101"}, trace)
+}
+
+func TestGroupRange(t *testing.T) {
+	r := Setup()
+	login := r.Group(1000, 1999)
+	login.Handle(1000, func(ctx *Context) {})
+
+	// 协议或子分组超出区间
+	assert.Panics(t, func() { login.Handle(2000, func(ctx *Context) {}) })
+	assert.Panics(t, func() { login.Group(1500, 2500) })
+	assert.Panics(t, func() { r.Group(2999, 2000) })
+
+	// 分组与根路由共享协议表
+	assert.Panics(t, func() { r.Handle(1000, func(ctx *Context) {}) })
+}
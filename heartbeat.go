@@ -0,0 +1,68 @@
+package orbit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ProtocolPing 保留协议，心跳请求，收到后自动回复 ProtocolPong
+	ProtocolPing uint32 = math.MaxUint32 - 1
+	// ProtocolPong 保留协议，心跳响应
+	ProtocolPong uint32 = math.MaxUint32 - 2
+)
+
+// heartbeat 心跳配置
+type heartbeat struct {
+	interval time.Duration
+	misses   int
+}
+
+// heartbeatProcessor 心跳处理器，每个间隔内没有收到任何数据时发送心跳请求，连续 misses 个心跳请求后的间隔都没有收到数据则关闭连接
+func (c *connection) heartbeatProcessor() {
+	ticker := time.NewTicker(c.heartbeat.interval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if atomic.SwapInt32(&c.active, 0) == 1 {
+				misses = 0
+				continue
+			}
+
+			// 已发送的心跳请求都没有得到响应
+			if misses >= c.heartbeat.misses {
+				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s heartbeat timeout, missed %d", c.RemoteAddr(), misses))
+				c.closeWith(newCloseError(CloseIdleTimeout, nil))
+				return
+			}
+
+			// 先发送心跳请求，下一个间隔仍没有收到数据时计为一次未响应
+			if err := c.Send(ProtocolPing, nil); err != nil {
+				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s send ping err: %e", c.RemoteAddr(), err))
+			}
+			misses++
+		}
+	}
+}
+
+// handleHeartbeat 处理心跳消息，收到心跳请求时回复心跳响应，返回是否为心跳消息
+func (c *connection) handleHeartbeat(msg Message) bool {
+	switch msg.GetProtocol() {
+	case ProtocolPing:
+		if err := c.Send(ProtocolPong, nil); err != nil {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s send pong err: %e", c.RemoteAddr(), err))
+		}
+		return true
+	case ProtocolPong:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package orbit
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIdleTimeout(t *testing.T) {
+	srv := New(WithRouter(Setup()), WithReadIdleTimeout(100*time.Millisecond))
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		srv.ServeConn(server)
+		close(done)
+	}()
+
+	// 没有发送任何数据，连接在空闲超时后关闭
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("idle connection not closed")
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write([]byte("never read"))
+	})
+	srv := New(WithRouter(r), WithWriteTimeout(100*time.Millisecond))
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		srv.ServeConn(server)
+		close(done)
+	}()
+
+	// 对端不读取响应，写超时后连接关闭
+	send, _ := NewDataPacket().Pack(NewMessagePacket(1, nil))
+	if _, err := client.Write(send); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("slow consumer not closed")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	srv := New(WithIP("127.0.0.1"), WithPort(11123), WithRouter(Setup()), WithHeartbeat(50*time.Millisecond, 3))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	mgr := srv.(*listener).mgr
+
+	// 客户端自动回复心跳，连接保持
+	c, err := Dial("127.0.0.1:11123")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// 不回复心跳的连接会收到心跳请求并被关闭
+	conn, err := net.Dial("tcp", "127.0.0.1:11123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	dp := NewDataPacket()
+	head := make([]byte, dp.GetHeadLength())
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = io.ReadFull(conn, head); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := dp.Unpack(head, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ProtocolPing, msg.GetProtocol())
+	}
+	assert.Eventually(t, func() bool { return mgr.Len() == 1 }, time.Second, 20*time.Millisecond)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, mgr.Len())
+}
+
+func TestHeartbeatMisses(t *testing.T) {
+	for _, misses := range []int{1, 3} {
+		srv := New(WithRouter(Setup()), WithHeartbeat(20*time.Millisecond, misses))
+
+		server, client := net.Pipe()
+		go srv.ServeConn(server)
+
+		// 关闭前发送 misses 个心跳请求
+		dp := NewDataPacket()
+		head := make([]byte, dp.GetHeadLength())
+		pings := 0
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			if _, err := io.ReadFull(client, head); err != nil {
+				assert.ErrorIs(t, err, io.EOF)
+				break
+			}
+			msg, err := dp.Unpack(head, 0)
+			if assert.NoError(t, err) {
+				assert.Equal(t, ProtocolPing, msg.GetProtocol())
+			}
+			pings++
+		}
+		assert.Equal(t, misses, pings)
+
+		client.Close()
+		srv.Off()
+	}
+}
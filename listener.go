@@ -1,27 +1,41 @@
 package orbit
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Server 监听者接口
 type Server interface {
 	Run() error
 	On() error
+	ServeListener(lis net.Listener) error
+	ServeConn(conn net.Conn) error
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
 	Off() error
+	Shutdown(ctx context.Context) error
+	Manager() Manager
 }
 
 // listener 监听器结构体
 type listener struct {
 	opts options
-	lis  *net.TCPListener
+	lis  net.Listener
+	pc   net.PacketConn
 
-	mgr    Manager
+	draining int32
+
+	mgr    *manager
 	router Router
 	work   Worker
 }
@@ -37,6 +51,13 @@ func New(opts ...Option) Server {
 		conns:   512,
 		tasks:   1024,
 		packet:  4096,
+
+		sendQueue:   1024,
+		sendTimeout: 5 * time.Millisecond,
+		writeBatch:  64 * 1024,
+
+		serializer:  NewJSONSerializer(),
+		sessionIdle: time.Minute,
 	}
 
 	// 加载自定义配置
@@ -50,16 +71,16 @@ func New(opts ...Option) Server {
 
 	return &listener{
 		opts: o,
-		mgr:  &manager{conns: make(map[string]Connection)},
-		work: &worker{
-			poolSize:  o.pool,
-			taskLen:   o.tasks,
-			taskQueue: make([]chan *Context, o.pool),
-			router:    o.router,
-		},
+		mgr:  newManager(o),
+		work: newWorker(o),
 	}
 }
 
+// Manager 获取连接管理
+func (l *listener) Manager() Manager {
+	return l.mgr
+}
+
 // Run 开始监听服务
 func (l *listener) Run() error {
 	go func() {
@@ -76,52 +97,110 @@ func (l *listener) Run() error {
 	return l.Off()
 }
 
+// On 按配置的网络和地址监听并开始服务
 func (l *listener) On() error {
 	log.Println(fmt.Sprintf("[ LISTENER ] startup"))
 
-	// 解析地址
-	address := fmt.Sprintf("%s:%d", l.opts.ip, l.opts.port)
-	addr, err := net.ResolveTCPAddr(l.opts.network, address)
-	if err != nil {
-		return err
+	// 未指定完整地址时使用 ip 和端口
+	address := l.opts.address
+	if address == "" {
+		address = fmt.Sprintf("%s:%d", l.opts.ip, l.opts.port)
 	}
 
-	// 监听对应端口
-	lis, err := net.ListenTCP(l.opts.network, addr)
+	atomic.StoreInt32(&l.draining, 0)
+
+	// 数据报网络
+	if strings.HasPrefix(l.opts.network, "udp") {
+		pc, err := net.ListenPacket(l.opts.network, address)
+		if err != nil {
+			return err
+		}
+		log.Println(fmt.Sprintf("[ LISTENER ] listen on %s %s", l.opts.network, address))
+
+		return l.serveUDP(pc)
+	}
+
+	// 监听对应地址
+	lis, err := net.Listen(l.opts.network, address)
 	if err != nil {
 		return err
 	}
+	log.Println(fmt.Sprintf("[ LISTENER ] listen on %s %s", l.opts.network, address))
+
+	return l.ServeListener(lis)
+}
+
+// ServeListener 在已有的监听器上开始服务，阻塞直到监听器关闭
+func (l *listener) ServeListener(lis net.Listener) error {
 	l.lis = lis
-	log.Println(fmt.Sprintf("[ LISTENER ] listen on %s:%d", l.opts.ip, l.opts.port))
 
 	// 启用工作池机制
-	l.work.UseWorkerPool()
+	l.work.Start()
+
+	// 使用 WebSocket 传输
+	if l.opts.websocket != "" {
+		return l.serveWebSocket(lis)
+	}
 
 	for {
 		// 阻塞等待客户端建立连接
-		conn, e := l.lis.AcceptTCP()
+		conn, e := l.lis.Accept()
 		if e != nil {
 			// 如果 listener 已关闭
 			if errors.Is(e, net.ErrClosed) {
 				return nil
 			}
-			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s accept err: %e", conn.RemoteAddr().String(), e))
+			log.Println(fmt.Sprintf("[ LISTENER ] accept err: %e", e))
 			continue
 		}
-		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s established", conn.RemoteAddr().String()))
 
-		// 如果当前连接数量超过最大连接数，则关闭新的连接
-		if l.mgr.Len() >= l.opts.conns {
-			conn.Close()
-			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s because limit connect is ignored", conn.RemoteAddr().String()))
-			continue
-		}
+		// 开启协程接入并处理当前连接任务，连接建立的回调不会阻塞接收新连接
+		go func(conn net.Conn) {
+			if l.opts.tls != nil {
+				conn = tls.Server(conn, l.opts.tls)
+			}
+			l.serve(conn)
+		}(conn)
+	}
+}
 
-		// 开启协程处理当前连接任务
-		go newConnection(conn, l.mgr, l.work, l.opts.packet).Handle()
+// ServeConn 处理单个已建立的连接，阻塞直到连接关闭
+func (l *listener) ServeConn(conn net.Conn) error {
+	// 启用工作池机制
+	l.work.Start()
+
+	if l.opts.tls != nil {
+		conn = tls.Server(conn, l.opts.tls)
 	}
+	return l.serve(conn)
+}
+
+// serve 接入并处理连接，阻塞直到连接关闭，连接被拒绝时返回原因
+func (l *listener) serve(conn net.Conn) error {
+	c, err := l.accept(conn)
+	if err != nil {
+		return err
+	}
+
+	c.Handle()
+	return c.rejected
 }
 
+// accept 接入连接，如果当前连接数量超过最大连接数，则关闭新的连接，等待建立的连接同样占用名额
+func (l *listener) accept(conn net.Conn) (*connection, error) {
+	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s established", conn.RemoteAddr().String()))
+
+	c := newConnection(conn, l.mgr, l.work, l.opts)
+	if !l.mgr.reserve(c, l.opts.conns) {
+		conn.Close()
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s because limit connect is ignored", conn.RemoteAddr().String()))
+		return nil, ErrTooManyConns
+	}
+
+	return c, nil
+}
+
+// Off 立即停止服务并关闭所有连接
 func (l *listener) Off() error {
 	log.Println(fmt.Sprintf("[ LISTENER ] listener is closeing"))
 
@@ -129,10 +208,64 @@ func (l *listener) Off() error {
 	l.mgr.Clear()
 
 	// 停止监听
-	if e := l.lis.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+	if e := l.close(); e != nil {
 		return e
 	}
 
+	// 停止工作池
+	l.work.Stop()
+
 	log.Println(fmt.Sprintf("[ LISTENER ] closed"))
 	return nil
 }
+
+// Shutdown 优雅停止服务，停止接收新连接，等待已读取的消息处理完成并将响应写出后关闭连接，超过 ctx 期限则强制关闭
+func (l *listener) Shutdown(ctx context.Context) error {
+	log.Println(fmt.Sprintf("[ LISTENER ] listener is shutting down"))
+
+	// 停止监听，数据报连接还需要用于写出响应，只停止读取
+	if l.pc != nil {
+		atomic.StoreInt32(&l.draining, 1)
+		if e := l.pc.SetReadDeadline(time.Now()); e != nil && !errors.Is(e, net.ErrClosed) {
+			return e
+		}
+	}
+	if l.lis != nil {
+		if e := l.lis.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			return e
+		}
+	}
+
+	// 等待所有连接处理完成，超时则强制关闭
+	e := l.mgr.Shutdown(ctx)
+	if e != nil {
+		l.mgr.Clear()
+	}
+
+	// 停止工作池
+	l.work.Stop()
+	if err := l.close(); err != nil && e == nil {
+		e = err
+	}
+	if e != nil {
+		return e
+	}
+
+	log.Println(fmt.Sprintf("[ LISTENER ] closed"))
+	return nil
+}
+
+// close 关闭监听器和数据报连接
+func (l *listener) close() error {
+	if l.lis != nil {
+		if e := l.lis.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			return e
+		}
+	}
+	if l.pc != nil {
+		if e := l.pc.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			return e
+		}
+	}
+	return nil
+}
@@ -1,17 +1,18 @@
 package orbit
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNew(t *testing.T) {
-
-	go Client4New()
-
 	r := Setup()
 	r.Handle(1, func(ctx *Context) {
 		fmt.Printf("[ SERVER ] receive msg form client: protocol = %d, len = %d, data = %s\n",  ctx.Protocol(), len(ctx.RawData()), string(ctx.RawData()))
@@ -19,7 +20,15 @@ func TestNew(t *testing.T) {
 	})
 
 	srv := New(WithRouter(r))
-	if e := srv.Run(); e != nil {
+	go func() {
+		if e := srv.On(); e != nil {
+			t.Error(e)
+		}
+	}()
+
+	// 客户端收到全部回复后停止服务，不向测试进程发送信号
+	Client4New()
+	if e := srv.Off(); e != nil {
 		t.Error(e)
 	}
 }
@@ -73,3 +82,218 @@ func Client4New() {
 
 	conn.Close()
 }
+
+func TestShutdown(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		time.Sleep(100 * time.Millisecond)
+		ctx.Write(ctx.RawData())
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11114), WithRouter(r))
+	go srv.On()
+	time.Sleep(100 * time.Millisecond)
+
+	reply := make(chan []byte, 8)
+	cr := Setup()
+	cr.Handle(1, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+	c, err := Dial("127.0.0.1:11114", WithRouter(cr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.Send(1, []byte{byte(i)}))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// 已读取的消息全部处理并写出后才关闭
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, srv.Shutdown(ctx))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case data := <-reply:
+			assert.Equal(t, []byte{byte(i)}, data)
+		case <-time.After(time.Second):
+			t.Fatalf("reply %d lost after shutdown", i)
+		}
+	}
+
+	// 停止服务后不再接收新连接
+	_, err = Dial("127.0.0.1:11114")
+	assert.Error(t, err)
+}
+
+func TestShutdownConcurrent(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	connected := make(chan Connection, 1)
+	srv := New(WithRouter(r), WithOnConnect(func(conn Connection) error {
+		connected <- conn
+		return nil
+	}))
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go srv.ServeConn(server)
+	conn := <-connected
+
+	// 处理中的消息使排空过程持续一段时间
+	send, _ := NewDataPacket().Pack(NewMessagePacket(1, nil))
+	if _, err := client.Write(send); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// 同时优雅关闭同一个连接和整个服务
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	errs := make(chan error, 3)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- conn.Shutdown(ctx)
+		}()
+	}
+	go func() {
+		errs <- srv.Shutdown(ctx)
+	}()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		time.Sleep(time.Second)
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11115), WithRouter(r))
+	go srv.On()
+	time.Sleep(100 * time.Millisecond)
+
+	c, err := Dial("127.0.0.1:11115")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, c.Send(1, nil))
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, srv.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestRestart(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11116), WithRouter(r))
+	for i := 0; i < 2; i++ {
+		go srv.On()
+		time.Sleep(100 * time.Millisecond)
+
+		reply := make(chan []byte, 1)
+		cr := Setup()
+		cr.Handle(1, func(ctx *Context) {
+			reply <- ctx.RawData()
+		})
+		c, err := Dial("127.0.0.1:11116", WithRouter(cr))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.NoError(t, c.Send(1, []byte("restart")))
+		select {
+		case data := <-reply:
+			assert.Equal(t, []byte("restart"), data)
+		case <-time.After(time.Second):
+			t.Errorf("round %d receive reply timeout", i)
+		}
+
+		c.Close()
+		assert.NoError(t, srv.Off())
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+	srv := New(WithRouter(r))
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ServeConn(server)
+	}()
+
+	// 直接按帧格式读写内存连接
+	dp := NewDataPacket()
+	send, _ := dp.Pack(NewMessagePacket(1, []byte("pipe")))
+	client.SetDeadline(time.Now().Add(time.Second))
+	if _, err := client.Write(send); err != nil {
+		t.Fatal(err)
+	}
+	resp := make([]byte, len(send))
+	if _, err := io.ReadFull(client, resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, send, resp)
+
+	// 对端关闭后 ServeConn 返回
+	client.Close()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Error("serve conn not return after peer closed")
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "orbit.sock")
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+	srv := New(WithNetwork("unix"), WithAddress(address), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	reply := make(chan []byte, 1)
+	cr := Setup()
+	cr.Handle(1, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+	c, err := Dial(address, WithNetwork("unix"), WithRouter(cr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, c.Send(1, []byte("unix")))
+	select {
+	case data := <-reply:
+		assert.Equal(t, []byte("unix"), data)
+	case <-time.After(time.Second):
+		t.Error("client receive reply timeout")
+	}
+}
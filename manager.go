@@ -1,25 +1,65 @@
 package orbit
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log"
 	"sync"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // Manager 连接管理接口
 type Manager interface {
 	Add(conn Connection)
 	Get(addr string) (Connection, error)
+	GetByID(id uint64) (Connection, error)
+	Bind(uid string, conn Connection) Connection
+	Unbind(uid string)
+	GetByUser(uid string) (Connection, error)
 	Len() int
+	Range(fn func(conn Connection) bool)
+	Broadcast(protocol uint32, data []byte) (map[uint64]error, error)
+	Multicast(ids []uint64, protocol uint32, data []byte) (map[uint64]error, error)
+	Rooms() Rooms
 	Del(conn Connection)
 	Clear()
+	Shutdown(ctx context.Context) error
 }
 
 // manager 连接管理结构体
 type manager struct {
+	dp    Packet
+	rooms *rooms
 	lock  sync.RWMutex
-	conns map[string]Connection
+	conns map[uint64]Connection
+	addrs map[string]Connection
+	users map[string]Connection
+	uids  map[uint64]string
+
+	// 等待连接建立的回调通过的连接，不对外可见，但计入最大连接数
+	pending map[uint64]Connection
+}
+
+// newManager 创建连接管理
+func newManager(o options) *manager {
+	m := &manager{
+		dp:    newPacket(o),
+		conns: make(map[uint64]Connection),
+		addrs: make(map[string]Connection),
+		users: make(map[string]Connection),
+		uids:  make(map[uint64]string),
+
+		pending: make(map[uint64]Connection),
+	}
+	m.rooms = newRooms(m, o)
+
+	return m
+}
+
+// Rooms 获取房间
+func (m *manager) Rooms() Rooms {
+	return m.rooms
 }
 
 // Add 添加连接
@@ -27,47 +67,247 @@ func (m *manager) Add(conn Connection) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	m.conns[conn.RemoteAddr()] = conn
+	delete(m.pending, conn.ID())
+	m.conns[conn.ID()] = conn
+	m.addrs[conn.RemoteAddr()] = conn
 
-	log.Println(fmt.Sprintf("[ MANAGER ] remote addr %s add to connection manager, current connections: %d", conn.RemoteAddr(), m.Len()))
+	log.Println(fmt.Sprintf("[ MANAGER ] connection %d remote addr %s add to connection manager, current connections: %d", conn.ID(), conn.RemoteAddr(), len(m.conns)))
 }
 
-// Get 获取当前连接
+// reserve 连接数量未达到上限时占用一个名额，连接在建立完成并加入连接管理前处于等待状态
+func (m *manager) reserve(conn Connection, max int) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.conns)+len(m.pending) >= max {
+		return false
+	}
+	m.pending[conn.ID()] = conn
+	return true
+}
+
+// Get 根据远程地址获取连接，地址重复时为最后加入的连接
 func (m *manager) Get(addr string) (Connection, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	if conn, ok := m.conns[addr]; ok {
+	if conn, ok := m.addrs[addr]; ok {
+		return conn, nil
+	}
+
+	return nil, ErrConnNotFound
+}
+
+// GetByID 根据连接 id 获取连接
+func (m *manager) GetByID(id uint64) (Connection, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if conn, ok := m.conns[id]; ok {
+		return conn, nil
+	}
+
+	return nil, ErrConnNotFound
+}
+
+// Bind 将用户绑定到连接，返回该用户之前绑定的其他连接，用于踢掉重复登录
+func (m *manager) Bind(uid string, conn Connection) Connection {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// 连接之前绑定了其他用户
+	if old, ok := m.uids[conn.ID()]; ok && old != uid {
+		delete(m.users, old)
+	}
+
+	prev, ok := m.users[uid]
+	if ok && prev.ID() != conn.ID() {
+		delete(m.uids, prev.ID())
+	} else {
+		prev = nil
+	}
+
+	m.users[uid] = conn
+	m.uids[conn.ID()] = uid
+	log.Println(fmt.Sprintf("[ MANAGER ] connection %d bind user %s", conn.ID(), uid))
+
+	return prev
+}
+
+// Unbind 解除用户绑定
+func (m *manager) Unbind(uid string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if conn, ok := m.users[uid]; ok {
+		delete(m.uids, conn.ID())
+		delete(m.users, uid)
+	}
+}
+
+// GetByUser 根据用户获取连接
+func (m *manager) GetByUser(uid string) (Connection, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if conn, ok := m.users[uid]; ok {
 		return conn, nil
 	}
 
-	return nil, errors.New("connection not found")
+	return nil, ErrConnNotFound
 }
 
 // Len 获取当前连接总数
 func (m *manager) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
 	return len(m.conns)
 }
 
-// Del 关闭并删除连接
+// Range 遍历当前所有连接，fn 返回 false 时停止遍历
+func (m *manager) Range(fn func(conn Connection) bool) {
+	for _, conn := range m.snapshot() {
+		if !fn(conn) {
+			return
+		}
+	}
+}
+
+// Broadcast 向所有连接发送数据，返回发送失败的连接 id 及原因
+func (m *manager) Broadcast(protocol uint32, data []byte) (map[uint64]error, error) {
+	return m.send(m.snapshot(), nil, protocol, data)
+}
+
+// Multicast 向指定 id 的连接发送数据，返回发送失败的连接 id 及原因
+func (m *manager) Multicast(ids []uint64, protocol uint32, data []byte) (map[uint64]error, error) {
+	failed := make(map[uint64]error)
+
+	m.lock.RLock()
+	conns := make([]Connection, 0, len(ids))
+	for _, id := range ids {
+		if conn, ok := m.conns[id]; ok {
+			conns = append(conns, conn)
+		} else {
+			failed[id] = ErrConnNotFound
+		}
+	}
+	m.lock.RUnlock()
+
+	return m.send(conns, failed, protocol, data)
+}
+
+// send 只封包一次，将相同的数据加入每个连接的发送队列
+func (m *manager) send(conns []Connection, failed map[uint64]error, protocol uint32, data []byte) (map[uint64]error, error) {
+	if failed == nil {
+		failed = make(map[uint64]error)
+	}
+
+	buff, err := m.dp.Pack(NewMessagePacket(protocol, data))
+	if err != nil {
+		return nil, fmt.Errorf("pack msg: %w", err)
+	}
+
+	for _, conn := range conns {
+		// 数据报会话等使用其他封包方式的连接单独发送
+		var e error
+		if rs, ok := conn.(rawSender); ok {
+			e = rs.sendRaw(buff)
+		} else {
+			e = conn.Send(protocol, data)
+		}
+		if e != nil {
+			failed[conn.ID()] = e
+		}
+	}
+
+	if len(failed) > 0 {
+		log.Println(fmt.Sprintf("[ MANAGER ] send protocol %d to %d connections, failed: %d", protocol, len(conns), len(failed)))
+	}
+	return failed, nil
+}
+
+// rawSender 可以直接发送已封包数据的连接
+type rawSender interface {
+	sendRaw(buff []byte) error
+}
+
+// snapshot 获取当前所有连接的快照，遍历时不持有锁
+func (m *manager) snapshot() []Connection {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	conns := make([]Connection, 0, len(m.conns))
+	for _, conn := range m.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Del 关闭并删除连接，同时离开所有房间
 func (m *manager) Del(conn Connection) {
 	m.lock.Lock()
-	defer m.lock.Unlock()
+	m.del(conn)
+	log.Println(fmt.Sprintf("[ MANAGER ] connection %d remote addr %s remove from connection manager, current connections: %d", conn.ID(), conn.RemoteAddr(), len(m.conns)))
+	m.lock.Unlock()
 
-	delete(m.conns, conn.RemoteAddr())
-	log.Println(fmt.Sprintf("[ MANAGER ] remote addr %s remove from connection manager, current connections: %d", conn.RemoteAddr(), m.Len()))
+	// 不持有连接管理的锁，避免与房间的锁互相等待
+	m.rooms.LeaveAll(conn)
+}
+
+// del 删除连接及其地址索引和用户绑定，地址索引已被其他连接占用时保留
+func (m *manager) del(conn Connection) {
+	delete(m.pending, conn.ID())
+	delete(m.conns, conn.ID())
+	if c, ok := m.addrs[conn.RemoteAddr()]; ok && c == conn {
+		delete(m.addrs, conn.RemoteAddr())
+	}
+	if uid, ok := m.uids[conn.ID()]; ok {
+		delete(m.uids, conn.ID())
+		delete(m.users, uid)
+	}
 }
 
 // Clear 清除并停止所有连接
 func (m *manager) Clear() {
 	m.lock.Lock()
-	defer m.lock.Unlock()
+	conns := make([]Connection, 0, len(m.conns))
+	for _, conn := range m.conns {
+		closeConn(conn, newCloseError(CloseShutdown, nil))
+		m.del(conn)
+		conns = append(conns, conn)
+		log.Println(fmt.Sprintf("[ MANAGER ] connection %d remote addr %s remove from connection manager, current connections: %d", conn.ID(), conn.RemoteAddr(), len(m.conns)))
+	}
+	// 等待建立的连接同样关闭
+	for _, conn := range m.pending {
+		closeConn(conn, newCloseError(CloseShutdown, nil))
+		m.del(conn)
+	}
+	log.Println(fmt.Sprintf("[ MANAGER ] clear all connections, current connections: %d", len(m.conns)))
+	m.lock.Unlock()
+
+	// 不持有连接管理的锁，避免与房间的锁互相等待
+	for _, conn := range conns {
+		m.rooms.LeaveAll(conn)
+	}
+}
+
+// Shutdown 优雅关闭所有连接，包括等待建立的连接
+func (m *manager) Shutdown(ctx context.Context) error {
+	conns := m.snapshot()
+	m.lock.RLock()
+	for _, conn := range m.pending {
+		conns = append(conns, conn)
+	}
+	m.lock.RUnlock()
 
-	for addr, conn := range m.conns {
-		conn.Close()
-		delete(m.conns, addr)
-		log.Println(fmt.Sprintf("[ MANAGER ] remote addr %s remove from connection manager, current connections: %d", conn.RemoteAddr(), m.Len()))
+	var g errgroup.Group
+	for _, conn := range conns {
+		conn := conn
+		g.Go(func() error {
+			return conn.Shutdown(ctx)
+		})
 	}
 
-	log.Println(fmt.Sprintf("[ MANAGER ] clear all connections, current connections: %d", m.Len()))
+	return g.Wait()
 }
@@ -0,0 +1,148 @@
+package orbit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	m := newManager(options{})
+
+	// 地址相同的连接通过 id 区分
+	a := &mockConn{id: nextConnID(), addr: "10.0.0.1:4399"}
+	b := &mockConn{id: nextConnID(), addr: "10.0.0.1:4399"}
+	assert.Less(t, a.ID(), b.ID())
+
+	m.Add(a)
+	m.Add(b)
+	assert.Equal(t, 2, m.Len())
+
+	conn, err := m.GetByID(a.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, a, conn)
+
+	// 地址索引指向最后加入的连接，删除旧连接不影响新连接的索引
+	conn, err = m.Get("10.0.0.1:4399")
+	assert.NoError(t, err)
+	assert.Equal(t, b, conn)
+
+	m.Del(a)
+	assert.Equal(t, 1, m.Len())
+	_, err = m.GetByID(a.ID())
+	assert.Error(t, err)
+	conn, err = m.Get("10.0.0.1:4399")
+	assert.NoError(t, err)
+	assert.Equal(t, b, conn)
+
+	m.Del(b)
+	_, err = m.Get("10.0.0.1:4399")
+	assert.Error(t, err)
+}
+
+func TestManagerBind(t *testing.T) {
+	m := newManager(options{})
+
+	a := &mockConn{id: nextConnID(), addr: "10.0.0.1:1"}
+	b := &mockConn{id: nextConnID(), addr: "10.0.0.2:1"}
+	m.Add(a)
+	m.Add(b)
+
+	assert.Nil(t, m.Bind("1001", a))
+	assert.Nil(t, m.Bind("1001", a))
+	conn, err := m.GetByUser("1001")
+	assert.NoError(t, err)
+	assert.Equal(t, a, conn)
+
+	// 重复登录返回之前的连接
+	assert.Equal(t, a, m.Bind("1001", b))
+	conn, err = m.GetByUser("1001")
+	assert.NoError(t, err)
+	assert.Equal(t, b, conn)
+
+	// 旧连接删除不影响新的绑定
+	m.Del(a)
+	_, err = m.GetByUser("1001")
+	assert.NoError(t, err)
+
+	// 连接删除时解除绑定
+	m.Del(b)
+	_, err = m.GetByUser("1001")
+	assert.Error(t, err)
+
+	m.Add(a)
+	m.Bind("1002", a)
+	m.Unbind("1002")
+	_, err = m.GetByUser("1002")
+	assert.Error(t, err)
+}
+
+func TestManagerBroadcast(t *testing.T) {
+	srv := New(WithIP("127.0.0.1"), WithPort(11124), WithRouter(Setup()))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	received := make(chan string, 16)
+	for i := 0; i < 3; i++ {
+		cr := Setup()
+		cr.Handle(1, func(ctx *Context) {
+			received <- string(ctx.RawData())
+		})
+		c, err := Dial("127.0.0.1:11124", WithRouter(cr))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer c.Close()
+	}
+
+	mgr := srv.Manager()
+	assert.Eventually(t, func() bool { return mgr.Len() == 3 }, time.Second, 10*time.Millisecond)
+
+	var ids []uint64
+	mgr.Range(func(conn Connection) bool {
+		ids = append(ids, conn.ID())
+		return true
+	})
+	assert.Len(t, ids, 3)
+
+	// 遍历可以提前结束
+	n := 0
+	mgr.Range(func(conn Connection) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n)
+
+	failed, err := mgr.Broadcast(1, []byte("all"))
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	for i := 0; i < 3; i++ {
+		select {
+		case data := <-received:
+			assert.Equal(t, "all", data)
+		case <-time.After(time.Second):
+			t.Fatal("broadcast not received")
+		}
+	}
+
+	// 不存在的连接出现在失败结果中
+	failed, err = mgr.Multicast([]uint64{ids[0], ids[1], 0}, 1, []byte("some"))
+	assert.NoError(t, err)
+	assert.Len(t, failed, 1)
+	assert.Contains(t, failed, uint64(0))
+	for i := 0; i < 2; i++ {
+		select {
+		case data := <-received:
+			assert.Equal(t, "some", data)
+		case <-time.After(time.Second):
+			t.Fatal("multicast not received")
+		}
+	}
+	select {
+	case data := <-received:
+		t.Errorf("unexpected message: %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -4,10 +4,12 @@ package orbit
 type Message interface {
 	GetLength() uint32
 	GetProtocol() uint32
+	GetSeq() uint32
 	GetData() []byte
 
 	SetLength(length uint32)
 	SetProtocol(id uint32)
+	SetSeq(seq uint32)
 	SetData(data []byte)
 }
 
@@ -15,6 +17,7 @@ type Message interface {
 type message struct {
 	length   uint32
 	protocol uint32
+	seq      uint32
 	data     []byte
 }
 
@@ -37,6 +40,11 @@ func (msg *message) GetProtocol() uint32 {
 	return msg.protocol
 }
 
+// GetSeq 获取消息序列号
+func (msg *message) GetSeq() uint32 {
+	return msg.seq
+}
+
 // GetData 获取消息内容
 func (msg *message) GetData() []byte {
 	return msg.data
@@ -52,6 +60,11 @@ func (msg *message) SetProtocol(protocol uint32) {
 	msg.protocol = protocol
 }
 
+// SetSeq 设置消息序列号
+func (msg *message) SetSeq(seq uint32) {
+	msg.seq = seq
+}
+
 // SetData 设置消息内容
 func (msg *message) SetData(data []byte) {
 	msg.data = data
@@ -1,8 +1,11 @@
 package orbit
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 )
 
 // Option 选项闭包函数
@@ -13,16 +16,53 @@ type options struct {
 	network string
 	ip      string
 	port    int
+	address string
 
 	conns  int
 	pool   int
 	tasks  int
 	packet uint32
+	seq    bool
+	dp     Packet
+
+	serializer Serializer
+	tls        *tls.Config
+
+	websocket   string
+	checkOrigin func(r *http.Request) bool
+
+	sessionIdle time.Duration
+
+	readIdle     time.Duration
+	writeTimeout time.Duration
+	heartbeat    heartbeat
+
+	sendQueue   int
+	sendPolicy  SendPolicy
+	sendTimeout time.Duration
+	writeBatch  int
+	bufferPool  bool
+
+	onRoomCreate  func(room string)
+	onRoomDestroy func(room string)
+
+	onConnect    func(conn Connection) error
+	onDisconnect func(conn Connection, reason error)
 
 	signals []os.Signal
-	router Router
+	router  Router
+
+	onWorkerStart func(wid int)
+	onWorkerStop  func(wid int)
+
+	onPanic      PanicHandler
+	closeOnPanic bool
+	maxNoRoute   uint32
 }
 
+// PanicHandler 处理方法 panic 时的回调
+type PanicHandler func(ctx *Context, err interface{}, stack []byte)
+
 // WithNetwork 网络
 func WithNetwork(network string) Option {
 	return func(o *options) {
@@ -44,7 +84,14 @@ func WithPort(port int) Option {
 	}
 }
 
-// WithMaxConns 最大连接数
+// WithAddress 完整的监听地址，设置后忽略 ip 和端口，unix 网络时为 socket 文件路径
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithMaxConns 最大连接数，等待连接建立的回调通过的连接也占用名额
 func WithMaxConns(conns int) Option {
 	return func(o *options) {
 		o.conns = conns
@@ -75,6 +122,81 @@ func WithMaxMessagePacketSize(size uint32) Option {
 	}
 }
 
+// WithPacket 自定义数据包的封包拆包方式，服务端与客户端需要使用相同的数据包，
+// 数据包被所有连接共享，实现 StreamReader 时可以读取变长包头
+func WithPacket(dp Packet) Option {
+	return func(o *options) {
+		o.dp = dp
+	}
+}
+
+// WithSerializer 消息内容的序列化方式，默认为 JSON
+func WithSerializer(s Serializer) Option {
+	return func(o *options) {
+		o.serializer = s
+	}
+}
+
+// WithSequence 使用带序列号的数据包，用于在同一连接上并发请求响应，
+// 与 WithPacket 同时使用时表示自定义数据包支持序列号
+func WithSequence() Option {
+	return func(o *options) {
+		o.seq = true
+	}
+}
+
+// WithTLSConfig 使用 TLS 加密连接，服务端设置 ClientAuth 可以校验客户端证书
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithWebSocket 使用 WebSocket 传输，在 path 上接收连接
+func WithWebSocket(path string) Option {
+	return func(o *options) {
+		o.websocket = path
+	}
+}
+
+// WithCheckOrigin WebSocket 握手时校验请求来源，默认只允许同源
+func WithCheckOrigin(fn func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.checkOrigin = fn
+	}
+}
+
+// WithSessionIdleTimeout UDP 会话的空闲超时，超过该时间没有收到数据报则关闭会话，默认一分钟，小于等于 0 时不检测
+func WithSessionIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.sessionIdle = d
+	}
+}
+
+// WithReadIdleTimeout 读空闲超时，超过该时间没有收到数据则关闭连接
+func WithReadIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readIdle = d
+	}
+}
+
+// WithWriteTimeout 写超时，单次写出超过该时间则关闭连接
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = d
+	}
+}
+
+// WithHeartbeat 每隔 interval 没有收到数据时发送心跳请求，连续发送 misses 个心跳请求后仍没有收到数据则关闭连接
+func WithHeartbeat(interval time.Duration, misses int) Option {
+	if misses < 1 {
+		panic(fmt.Sprintf("heartbeat misses cannt less than 1"))
+	}
+	return func(o *options) {
+		o.heartbeat = heartbeat{interval: interval, misses: misses}
+	}
+}
+
 // WithSignal 停止服务信号
 func WithSignal(signals ...os.Signal) Option {
 	return func(o *options) {
@@ -88,3 +210,106 @@ func WithRouter(r Router) Option {
 		o.router = r
 	}
 }
+
+// WithOnWorkerStart worker 启动时的回调
+func WithOnWorkerStart(fn func(wid int)) Option {
+	return func(o *options) {
+		o.onWorkerStart = fn
+	}
+}
+
+// WithOnWorkerStop worker 退出时的回调
+func WithOnWorkerStop(fn func(wid int)) Option {
+	return func(o *options) {
+		o.onWorkerStop = fn
+	}
+}
+
+// WithPanicHandler 处理方法 panic 时的回调
+func WithPanicHandler(fn PanicHandler) Option {
+	return func(o *options) {
+		o.onPanic = fn
+	}
+}
+
+// WithCloseOnPanic 处理方法 panic 时关闭对应连接
+func WithCloseOnPanic() Option {
+	return func(o *options) {
+		o.closeOnPanic = true
+	}
+}
+
+// WithMaxNoRoute 连接请求未注册协议的次数达到上限时断开，0 表示不限制
+func WithMaxNoRoute(n uint32) Option {
+	return func(o *options) {
+		o.maxNoRoute = n
+	}
+}
+
+// WithOnRoomCreate 房间创建时的回调
+func WithOnRoomCreate(fn func(room string)) Option {
+	return func(o *options) {
+		o.onRoomCreate = fn
+	}
+}
+
+// WithOnRoomDestroy 房间最后一个成员离开后销毁时的回调
+func WithOnRoomDestroy(fn func(room string)) Option {
+	return func(o *options) {
+		o.onRoomDestroy = fn
+	}
+}
+
+// WithOnConnect 连接建立的回调，在连接自己的协程中 TLS 握手完成后执行，返回错误时拒绝该连接，通过后连接才加入连接管理
+func WithOnConnect(fn func(conn Connection) error) Option {
+	return func(o *options) {
+		o.onConnect = fn
+	}
+}
+
+// WithOnDisconnect 连接关闭并从连接管理中删除后的回调，被拒绝的连接不触发，reason 为 *CloseError
+func WithOnDisconnect(fn func(conn Connection, reason error)) Option {
+	return func(o *options) {
+		o.onDisconnect = fn
+	}
+}
+
+// WithSendQueueSize 每个连接发送队列的长度，为 0 时不缓冲，发送方等待写协程取走数据
+func WithSendQueueSize(size int) Option {
+	if size < 0 {
+		panic(fmt.Sprintf("send queue size cannt less than 0"))
+	}
+	return func(o *options) {
+		o.sendQueue = size
+	}
+}
+
+// WithSendPolicy 发送队列已满时的处理策略
+func WithSendPolicy(policy SendPolicy) Option {
+	return func(o *options) {
+		o.sendPolicy = policy
+	}
+}
+
+// WithSendTimeout 阻塞策略下 Send 等待发送队列的最长时间，小于等于 0 时一直等待到连接关闭
+func WithSendTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.sendTimeout = timeout
+	}
+}
+
+// WithMaxWriteBatchBytes 发送队列中等待的多个数据包合并为一次写出的最大字节数，小于等于 0 时每个数据包单独写出，
+// TCP 和 unix 连接使用向量写入，TLS、WebSocket 等连接复制到每个连接复用的缓冲区后写出
+func WithMaxWriteBatchBytes(size int) Option {
+	return func(o *options) {
+		o.writeBatch = size
+	}
+}
+
+// WithBufferPool 读取消息数据时使用缓冲池，处理方法执行完成后回收，
+// 启用后 Context.RawData 只在处理方法执行期间有效，需要保留时应复制
+func WithBufferPool() Option {
+	return func(o *options) {
+		o.bufferPool = true
+	}
+}
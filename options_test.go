@@ -2,8 +2,10 @@ package orbit
 
 import (
 	"github.com/stretchr/testify/assert"
+	"errors"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestWithNetwork(t *testing.T) {
@@ -71,8 +73,14 @@ func TestWithSignal(t *testing.T) {
 
 type mockRouter struct{}
 
-func (m *mockRouter) Handle(protocol uint32, handler HandlerFunc) {}
-func (m *mockRouter) exec(ctx *Context)                         {}
+func (m *mockRouter) Use(middleware ...HandlerFunc)                   {}
+func (m *mockRouter) Handle(protocol uint32, handlers ...HandlerFunc) {}
+func (m *mockRouter) NoRoute(handlers ...HandlerFunc)                 {}
+func (m *mockRouter) NoRouteCount() uint64                            { return 0 }
+func (m *mockRouter) exec(ctx *Context) bool                          { return true }
+func (m *mockRouter) Group(start, end uint32, middleware ...HandlerFunc) RouterGroup {
+	return m
+}
 
 func TestWithRouter(t *testing.T) {
 	o := &options{}
@@ -81,3 +89,156 @@ func TestWithRouter(t *testing.T) {
 	WithRouter(v)(o)
 	assert.Equal(t, v, o.router)
 }
+
+func TestWithOnWorkerStart(t *testing.T) {
+	o := &options{}
+	wid := -1
+	WithOnWorkerStart(func(id int) { wid = id })(o)
+	o.onWorkerStart(3)
+	assert.Equal(t, 3, wid)
+}
+
+func TestWithOnWorkerStop(t *testing.T) {
+	o := &options{}
+	wid := -1
+	WithOnWorkerStop(func(id int) { wid = id })(o)
+	o.onWorkerStop(5)
+	assert.Equal(t, 5, wid)
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	o := &options{}
+	var v interface{}
+	WithPanicHandler(func(ctx *Context, err interface{}, stack []byte) { v = err })(o)
+	o.onPanic(nil, "boom", nil)
+	assert.Equal(t, "boom", v)
+}
+
+func TestWithCloseOnPanic(t *testing.T) {
+	o := &options{}
+	WithCloseOnPanic()(o)
+	assert.True(t, o.closeOnPanic)
+}
+
+func TestWithMaxNoRoute(t *testing.T) {
+	o := &options{}
+	v := uint32(3)
+	WithMaxNoRoute(v)(o)
+	assert.Equal(t, v, o.maxNoRoute)
+}
+
+func TestWithPacket(t *testing.T) {
+	o := &options{}
+	v := NewSeqDataPacket()
+	WithPacket(v)(o)
+	assert.Equal(t, v, o.dp)
+	assert.Equal(t, v, newPacket(*o))
+}
+
+func TestWithSerializer(t *testing.T) {
+	o := &options{}
+	v := NewProtobufSerializer()
+	WithSerializer(v)(o)
+	assert.Equal(t, v, o.serializer)
+}
+
+func TestWithAddress(t *testing.T) {
+	o := &options{}
+	v := "/tmp/orbit.sock"
+	WithAddress(v)(o)
+	assert.Equal(t, v, o.address)
+}
+
+func TestWithSessionIdleTimeout(t *testing.T) {
+	o := &options{}
+	v := 30 * time.Second
+	WithSessionIdleTimeout(v)(o)
+	assert.Equal(t, v, o.sessionIdle)
+}
+
+func TestWithReadIdleTimeout(t *testing.T) {
+	o := &options{}
+	v := 30 * time.Second
+	WithReadIdleTimeout(v)(o)
+	assert.Equal(t, v, o.readIdle)
+}
+
+func TestWithWriteTimeout(t *testing.T) {
+	o := &options{}
+	v := 5 * time.Second
+	WithWriteTimeout(v)(o)
+	assert.Equal(t, v, o.writeTimeout)
+}
+
+func TestWithHeartbeat(t *testing.T) {
+	o := &options{}
+	WithHeartbeat(10*time.Second, 3)(o)
+	assert.Equal(t, heartbeat{interval: 10 * time.Second, misses: 3}, o.heartbeat)
+	assert.Panics(t, func() { WithHeartbeat(time.Second, 0) })
+}
+
+func TestWithOnRoomCreate(t *testing.T) {
+	o := &options{}
+	var v string
+	WithOnRoomCreate(func(room string) { v = room })(o)
+	o.onRoomCreate("lobby")
+	assert.Equal(t, "lobby", v)
+}
+
+func TestWithOnRoomDestroy(t *testing.T) {
+	o := &options{}
+	var v string
+	WithOnRoomDestroy(func(room string) { v = room })(o)
+	o.onRoomDestroy("lobby")
+	assert.Equal(t, "lobby", v)
+}
+
+func TestWithOnConnect(t *testing.T) {
+	o := &options{}
+	v := errors.New("rejected")
+	WithOnConnect(func(conn Connection) error { return v })(o)
+	assert.Equal(t, v, o.onConnect(nil))
+}
+
+func TestWithOnDisconnect(t *testing.T) {
+	o := &options{}
+	var v error
+	WithOnDisconnect(func(conn Connection, reason error) { v = reason })(o)
+	o.onDisconnect(nil, CloseKicked)
+	assert.Equal(t, CloseKicked, v)
+}
+
+func TestWithSendQueueSize(t *testing.T) {
+	o := &options{}
+	v := 256
+	WithSendQueueSize(v)(o)
+	assert.Equal(t, v, o.sendQueue)
+	assert.Panics(t, func() { WithSendQueueSize(-1) })
+}
+
+func TestWithSendPolicy(t *testing.T) {
+	o := &options{}
+	v := SendDropOldest
+	WithSendPolicy(v)(o)
+	assert.Equal(t, v, o.sendPolicy)
+}
+
+func TestWithSendTimeout(t *testing.T) {
+	o := &options{}
+	v := 50 * time.Millisecond
+	WithSendTimeout(v)(o)
+	assert.Equal(t, v, o.sendTimeout)
+}
+
+func TestWithMaxWriteBatchBytes(t *testing.T) {
+	o := &options{}
+	v := 16 * 1024
+	WithMaxWriteBatchBytes(v)(o)
+	assert.Equal(t, v, o.writeBatch)
+}
+
+func TestWithBufferPool(t *testing.T) {
+	o := &options{}
+	WithBufferPool()(o)
+	assert.True(t, o.bufferPool)
+}
@@ -1,21 +1,37 @@
 package orbit
 
 import (
-	"bytes"
 	"encoding/binary"
-	"errors"
+	"io"
 )
 
 // defaultHeadLength 消息头部长度
 const defaultHeadLength = 8
 
-// Packet 数据包接口
+// Packet 数据包接口，Unpack 解析的数据长度超过 maxSize 时应返回 ErrMessageTooLarge，
+// 同一个实例被所有连接并发使用，不能保存读写状态
 type Packet interface {
 	GetHeadLength() uint32
 	Pack(msg Message) ([]byte, error)
 	Unpack(data []byte, maxSize uint32) (Message, error)
 }
 
+// PacketAppender 支持追加封包的数据包，复用缓冲区时封包不分配内存
+type PacketAppender interface {
+	AppendPack(dst []byte, msg Message) ([]byte, error)
+}
+
+// StreamReader 自行从数据流读取完整消息的数据包，用于 varint 长度等变长包头，
+// 解码器优先使用，不再按 GetHeadLength 读取包头，消息长度超过 maxSize 时应返回 ErrMessageTooLarge
+type StreamReader interface {
+	ReadMessage(r io.Reader, maxSize uint32) (Message, error)
+}
+
+// HeadDecoder 支持将包头解析到已有消息的数据包，读取时复用消息不分配内存
+type HeadDecoder interface {
+	DecodeHead(head []byte, msg Message, maxSize uint32) error
+}
+
 // packet 数据包结构体
 type packet struct{}
 
@@ -30,55 +46,136 @@ func (pk *packet) GetHeadLength() uint32 {
 	return defaultHeadLength
 }
 
+// newPacket 根据配置选择数据包，优先使用自定义数据包
+func newPacket(o options) Packet {
+	if o.dp != nil {
+		return o.dp
+	}
+	if o.seq {
+		return NewSeqDataPacket()
+	}
+	return NewDataPacket()
+}
+
 // Pack 封包
 func (pk *packet) Pack(msg Message) ([]byte, error) {
-	// 创建缓冲区
-	buff := bytes.NewBuffer([]byte{})
+	return pk.AppendPack(make([]byte, 0, defaultHeadLength+len(msg.GetData())), msg)
+}
+
+// AppendPack 将消息封包后追加到 dst，dst 容量足够时不分配内存
+func (pk *packet) AppendPack(dst []byte, msg Message) ([]byte, error) {
+	n := len(dst)
+	dst = grow(dst, defaultHeadLength+len(msg.GetData()))
+
+	// 写数据长度、数据协议和数据内容
+	binary.LittleEndian.PutUint32(dst[n:], msg.GetLength())
+	binary.LittleEndian.PutUint32(dst[n+4:], msg.GetProtocol())
+	copy(dst[n+defaultHeadLength:], msg.GetData())
 
-	// 写数据长度
-	if err := binary.Write(buff, binary.LittleEndian, msg.GetLength()); err != nil {
+	return dst, nil
+}
+
+// Unpack 拆包
+func (pk *packet) Unpack(data []byte, maxSize uint32) (Message, error) {
+	msg := &message{}
+	if err := pk.DecodeHead(data, msg, maxSize); err != nil {
 		return nil, err
 	}
 
-	// 写数据协议
-	if err := binary.Write(buff, binary.LittleEndian, msg.GetProtocol()); err != nil {
-		return nil, err
+	// 通过 head 的长度，后续需要在从 conn 读取一次数据
+	return msg, nil
+}
+
+// DecodeHead 将包头解析到 msg，只获取 protocol 和 length
+func (pk *packet) DecodeHead(head []byte, msg Message, maxSize uint32) error {
+	if len(head) < defaultHeadLength {
+		return ErrTruncatedHead
 	}
 
-	// 写数据内容
-	if err := binary.Write(buff, binary.LittleEndian, msg.GetData()); err != nil {
-		return nil, err
+	msg.SetLength(binary.LittleEndian.Uint32(head))
+	msg.SetProtocol(binary.LittleEndian.Uint32(head[4:]))
+	msg.SetSeq(0)
+	msg.SetData(nil)
+
+	// 判断数据长度是否超过允许值
+	if maxSize > 0 && msg.GetLength() > maxSize {
+		return ErrMessageTooLarge
 	}
 
-	return buff.Bytes(), nil
+	return nil
 }
 
-// Unpack 拆包
-func (pk *packet) Unpack(data []byte, maxSize uint32) (Message, error) {
-	// 创建 io reader
-	buff := bytes.NewReader(data)
+// seqHeadLength 带序列号的消息头部长度
+const seqHeadLength = 12
+
+// seqPacket 带序列号的数据包结构体
+type seqPacket struct{}
+
+// NewSeqDataPacket 带序列号的数据包实例化
+func NewSeqDataPacket() Packet {
+	return &seqPacket{}
+}
+
+// GetHeadLength 获取包头长度
+func (pk *seqPacket) GetHeadLength() uint32 {
+	// msg.length 4 字节 + msg.protocol 4 字节 + msg.seq 4 字节
+	return seqHeadLength
+}
+
+// Pack 封包
+func (pk *seqPacket) Pack(msg Message) ([]byte, error) {
+	return pk.AppendPack(make([]byte, 0, seqHeadLength+len(msg.GetData())), msg)
+}
+
+// AppendPack 将消息封包后追加到 dst，dst 容量足够时不分配内存
+func (pk *seqPacket) AppendPack(dst []byte, msg Message) ([]byte, error) {
+	n := len(dst)
+	dst = grow(dst, seqHeadLength+len(msg.GetData()))
+
+	// 写数据长度、数据协议、数据序列号和数据内容
+	binary.LittleEndian.PutUint32(dst[n:], msg.GetLength())
+	binary.LittleEndian.PutUint32(dst[n+4:], msg.GetProtocol())
+	binary.LittleEndian.PutUint32(dst[n+8:], msg.GetSeq())
+	copy(dst[n+seqHeadLength:], msg.GetData())
 
-	// 读取消息长度
-	var length uint32
-	if err := binary.Read(buff, binary.LittleEndian, &length); err != nil {
+	return dst, nil
+}
+
+// Unpack 拆包
+func (pk *seqPacket) Unpack(data []byte, maxSize uint32) (Message, error) {
+	msg := &message{}
+	if err := pk.DecodeHead(data, msg, maxSize); err != nil {
 		return nil, err
 	}
 
-	// 读取消息协议
-	var protocol uint32
-	if err := binary.Read(buff, binary.LittleEndian, &protocol); err != nil {
-		return nil, err
+	return msg, nil
+}
+
+// DecodeHead 将包头解析到 msg，只获取 protocol、seq 和 length
+func (pk *seqPacket) DecodeHead(head []byte, msg Message, maxSize uint32) error {
+	if len(head) < seqHeadLength {
+		return ErrTruncatedHead
 	}
 
-	// 只解压 head 的消息，获取 protocol 和 length
-	msg := NewMessagePacket(protocol, []byte{})
-	msg.SetLength(length)
+	msg.SetLength(binary.LittleEndian.Uint32(head))
+	msg.SetProtocol(binary.LittleEndian.Uint32(head[4:]))
+	msg.SetSeq(binary.LittleEndian.Uint32(head[8:]))
+	msg.SetData(nil)
 
 	// 判断数据长度是否超过允许值
 	if maxSize > 0 && msg.GetLength() > maxSize {
-		return nil, errors.New("received too large message")
+		return ErrMessageTooLarge
 	}
 
-	// 通过 head 的长度，后续需要在从 conn 读取一次数据
-	return msg, nil
+	return nil
+}
+
+// grow 将 dst 的长度增加 n，容量不足时重新分配
+func grow(dst []byte, n int) []byte {
+	if cap(dst)-len(dst) < n {
+		buff := make([]byte, len(dst), len(dst)+n)
+		copy(buff, dst)
+		dst = buff
+	}
+	return dst[:len(dst)+n]
 }
@@ -1,6 +1,8 @@
 package orbit
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +18,7 @@ func TestNewDataPacket(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	defer lis.Close()
 
 	for {
 		conn, err := lis.Accept()
@@ -53,8 +56,6 @@ func TestNewDataPacket(t *testing.T) {
 			}
 		}
 	}
-
-	lis.Close()
 }
 
 func Client4NewDataPacket() {
@@ -84,3 +85,191 @@ func Client4NewDataPacket() {
 	conn.Write(append(sendData1, sendData2...))
 	conn.Close()
 }
+
+func TestNewSeqDataPacket(t *testing.T) {
+	dp := NewSeqDataPacket()
+
+	msg := NewMessagePacket(7, []byte("hello"))
+	msg.SetSeq(42)
+	buff, err := dp.Pack(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint32(len(buff)) != dp.GetHeadLength()+msg.GetLength() {
+		t.Fatalf("unexpected packet length: %d", len(buff))
+	}
+
+	head, err := dp.Unpack(buff[:dp.GetHeadLength()], 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.GetProtocol() != 7 || head.GetSeq() != 42 || head.GetLength() != 5 {
+		t.Fatalf("unexpected head: protocol=%d, seq=%d, length=%d", head.GetProtocol(), head.GetSeq(), head.GetLength())
+	}
+
+	if _, err = dp.Unpack(buff[:dp.GetHeadLength()], 4); err == nil {
+		t.Fatal("expected too large message error")
+	}
+}
+
+// bigEndianPacket 大端序、16 位协议号的自定义数据包
+type bigEndianPacket struct{}
+
+func (pk *bigEndianPacket) GetHeadLength() uint32 { return 6 }
+
+func (pk *bigEndianPacket) Pack(msg Message) ([]byte, error) {
+	buff := make([]byte, 6, 6+msg.GetLength())
+	binary.BigEndian.PutUint32(buff, msg.GetLength())
+	binary.BigEndian.PutUint16(buff[4:], uint16(msg.GetProtocol()))
+	return append(buff, msg.GetData()...), nil
+}
+
+func (pk *bigEndianPacket) Unpack(data []byte, maxSize uint32) (Message, error) {
+	msg := NewMessagePacket(uint32(binary.BigEndian.Uint16(data[4:])), []byte{})
+	msg.SetLength(binary.BigEndian.Uint32(data))
+	if maxSize > 0 && msg.GetLength() > maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	return msg, nil
+}
+
+func TestWithPacketRoundTrip(t *testing.T) {
+	dp := &bigEndianPacket{}
+
+	r := Setup()
+	r.Handle(513, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+	srv := New(WithIP("127.0.0.1"), WithPort(11118), WithPacket(dp), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	// 按自定义格式手动发送，确认服务端使用注入的数据包
+	conn, err := net.Dial("tcp", "127.0.0.1:11118")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte{0, 0, 0, 2, 2, 1, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+	resp := make([]byte, 8)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != string([]byte{0, 0, 0, 2, 2, 1, 'h', 'i'}) {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	// 客户端使用相同的数据包
+	reply := make(chan []byte, 1)
+	cr := Setup()
+	cr.Handle(513, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+	c, err := Dial("127.0.0.1:11118", WithPacket(dp), WithRouter(cr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err = c.Send(513, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-reply:
+		if string(data) != "hello" {
+			t.Fatalf("unexpected reply: %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client receive reply timeout")
+	}
+}
+
+func TestAppendPack(t *testing.T) {
+	for _, dp := range []Packet{NewDataPacket(), NewSeqDataPacket()} {
+		msg := NewMessagePacket(7, []byte("hello"))
+		msg.SetSeq(42)
+		want, _ := dp.Pack(msg)
+
+		// 追加到已有数据之后，容量足够时不重新分配
+		dst := make([]byte, 2, 64)
+		buff, err := dp.(PacketAppender).AppendPack(dst, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buff[2:]) != string(want) || &buff[0] != &dst[0] {
+			t.Fatalf("unexpected append pack: %v", buff)
+		}
+
+		// 解析到已有消息
+		head := &message{data: []byte("stale")}
+		if err = dp.(HeadDecoder).DecodeHead(buff[2:], head, 4096); err != nil {
+			t.Fatal(err)
+		}
+		if head.GetProtocol() != 7 || head.GetLength() != 5 || head.GetData() != nil {
+			t.Fatalf("unexpected head: protocol=%d, length=%d", head.GetProtocol(), head.GetLength())
+		}
+		if err = dp.(HeadDecoder).DecodeHead(buff[2:4], head, 4096); err == nil {
+			t.Fatal("expected message head too short error")
+		}
+	}
+}
+
+// reflectPack 使用 binary.Write 的封包方式，作为基准对比
+func reflectPack(msg Message) ([]byte, error) {
+	buff := bytes.NewBuffer([]byte{})
+	for _, v := range []interface{}{msg.GetLength(), msg.GetProtocol(), msg.GetData()} {
+		if err := binary.Write(buff, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+	return buff.Bytes(), nil
+}
+
+func BenchmarkPack(b *testing.B) {
+	dp := &packet{}
+	msg := NewMessagePacket(1, make([]byte, 128))
+
+	b.Run("binary.Write", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reflectPack(msg)
+		}
+	})
+	b.Run("Pack", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dp.Pack(msg)
+		}
+	})
+	b.Run("AppendPack", func(b *testing.B) {
+		b.ReportAllocs()
+		buff := make([]byte, 0, 256)
+		for i := 0; i < b.N; i++ {
+			buff, _ = dp.AppendPack(buff[:0], msg)
+		}
+	})
+}
+
+func BenchmarkUnpack(b *testing.B) {
+	dp := &packet{}
+	head, _ := dp.Pack(NewMessagePacket(1, nil))
+
+	b.Run("Unpack", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dp.Unpack(head, 4096)
+		}
+	})
+	b.Run("DecodeHead", func(b *testing.B) {
+		b.ReportAllocs()
+		msg := &message{}
+		for i := 0; i < b.N; i++ {
+			dp.DecodeHead(head, msg, 4096)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package orbit
+
+import (
+	"sync"
+)
+
+// maxPooledBuffer 回收到缓冲池的最大容量，超过时直接丢弃，避免长期占用大块内存
+const maxPooledBuffer = 64 * 1024
+
+// bufferPool 读取消息数据的缓冲池
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// getBuffer 从缓冲池获取长度为 n 的缓冲区，使用完成后需要调用 putBuffer 回收
+func getBuffer(n int) *[]byte {
+	buff := bufferPool.Get().(*[]byte)
+	if cap(*buff) < n {
+		*buff = make([]byte, n)
+	}
+	*buff = (*buff)[:n]
+	return buff
+}
+
+// putBuffer 回收缓冲区，回收后不能再使用
+func putBuffer(buff *[]byte) {
+	if buff == nil || cap(*buff) > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buff)
+}
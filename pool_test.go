@@ -0,0 +1,91 @@
+package orbit
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBuffer(t *testing.T) {
+	buff := getBuffer(16)
+	assert.Len(t, *buff, 16)
+	putBuffer(buff)
+
+	// 超过回收上限的缓冲区不回收
+	large := getBuffer(maxPooledBuffer + 1)
+	assert.Len(t, *large, maxPooledBuffer+1)
+	putBuffer(large)
+	putBuffer(nil)
+}
+
+func TestWithBufferPoolRoundTrip(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+	srv := New(WithRouter(r), WithBufferPool())
+	defer srv.Off()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go srv.ServeConn(server)
+
+	// 缓冲区被复用后数据仍然正确
+	dp := NewDataPacket()
+	client.SetDeadline(time.Now().Add(time.Second))
+	for _, data := range []string{"first message", "second", "third message!"} {
+		send, _ := dp.Pack(NewMessagePacket(1, []byte(data)))
+		if _, err := client.Write(send); err != nil {
+			t.Fatal(err)
+		}
+		resp := make([]byte, len(send))
+		if _, err := io.ReadFull(client, resp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, send, resp)
+	}
+}
+
+// benchWorker 直接完成任务的工作池，只统计读取消息的开销
+type benchWorker struct {
+	Worker
+	wg *sync.WaitGroup
+}
+
+func (w *benchWorker) JoinTaskQueue(ctx *Context) {
+	ctx.done()
+	w.wg.Done()
+}
+
+// BenchmarkReadProcessor 对比读取消息时每个数据包的内存分配
+func BenchmarkReadProcessor(b *testing.B) {
+	for _, pool := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pool=%t", pool), func(b *testing.B) {
+			server, client := net.Pipe()
+			defer client.Close()
+
+			var wg sync.WaitGroup
+			o := options{sendQueue: 1, bufferPool: pool}
+			c := newConnection(server, newManager(o), &benchWorker{wg: &wg}, o)
+			defer c.Close()
+			go c.readProcessor()
+
+			frame, _ := NewDataPacket().Pack(NewMessagePacket(1, make([]byte, 128)))
+			b.SetBytes(int64(len(frame)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			wg.Add(b.N)
+			for i := 0; i < b.N; i++ {
+				if _, err := client.Write(frame); err != nil {
+					b.Fatal(err)
+				}
+			}
+			wg.Wait()
+		})
+	}
+}
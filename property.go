@@ -0,0 +1,37 @@
+package orbit
+
+import "sync"
+
+// properties 连接属性结构体，并发安全
+type properties struct {
+	lock  sync.RWMutex
+	props map[string]interface{}
+}
+
+// Set 设置属性
+func (p *properties) Set(key string, value interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.props == nil {
+		p.props = make(map[string]interface{})
+	}
+	p.props[key] = value
+}
+
+// Get 获取属性
+func (p *properties) Get(key string) (interface{}, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	value, ok := p.props[key]
+	return value, ok
+}
+
+// Delete 删除属性
+func (p *properties) Delete(key string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.props, key)
+}
@@ -0,0 +1,45 @@
+package orbit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProperties(t *testing.T) {
+	c := &connection{}
+	ctx := &Context{conn: c}
+
+	assert.Equal(t, c, ctx.Connection())
+
+	_, ok := ctx.Get("uid")
+	assert.False(t, ok)
+
+	// 通过 Context 设置的属性保存在连接上
+	ctx.Set("uid", "1001")
+	v, ok := c.Get("uid")
+	assert.True(t, ok)
+	assert.Equal(t, "1001", v)
+
+	ctx.Delete("uid")
+	_, ok = c.Get("uid")
+	assert.False(t, ok)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i)
+			c.Set(key, i)
+			c.Get(key)
+		}(i)
+	}
+	wg.Wait()
+
+	v, ok = c.Get("key7")
+	assert.True(t, ok)
+	assert.Equal(t, 7, v)
+}
@@ -0,0 +1,148 @@
+package orbit
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Rooms 房间接口，用于按名称对连接分组发送消息
+type Rooms interface {
+	Join(room string, conn Connection) error
+	Leave(room string, conn Connection)
+	LeaveAll(conn Connection)
+	Members(room string) []Connection
+	Broadcast(room string, protocol uint32, data []byte) (map[uint64]error, error)
+}
+
+// rooms 房间结构体
+type rooms struct {
+	mgr *manager
+
+	lock   sync.RWMutex
+	rooms  map[string]map[uint64]Connection
+	joined map[uint64]map[string]struct{}
+
+	onCreate  func(room string)
+	onDestroy func(room string)
+}
+
+// newRooms 创建房间
+func newRooms(mgr *manager, o options) *rooms {
+	return &rooms{
+		mgr:       mgr,
+		rooms:     make(map[string]map[uint64]Connection),
+		joined:    make(map[uint64]map[string]struct{}),
+		onCreate:  o.onRoomCreate,
+		onDestroy: o.onRoomDestroy,
+	}
+}
+
+// Join 加入房间，房间不存在时创建，连接不在连接管理中时返回错误
+func (r *rooms) Join(room string, conn Connection) error {
+	if _, err := r.mgr.GetByID(conn.ID()); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	members, ok := r.rooms[room]
+	if !ok {
+		members = make(map[uint64]Connection)
+		r.rooms[room] = members
+	}
+	members[conn.ID()] = conn
+
+	if r.joined[conn.ID()] == nil {
+		r.joined[conn.ID()] = make(map[string]struct{})
+	}
+	r.joined[conn.ID()][room] = struct{}{}
+	r.lock.Unlock()
+
+	log.Println(fmt.Sprintf("[ ROOMS ] connection %d join room %s", conn.ID(), room))
+	if !ok {
+		log.Println(fmt.Sprintf("[ ROOMS ] room %s created", room))
+		if r.onCreate != nil {
+			r.onCreate(room)
+		}
+	}
+
+	// 加入期间连接已关闭，离开房间避免残留
+	if _, err := r.mgr.GetByID(conn.ID()); err != nil {
+		r.Leave(room, conn)
+		return fmt.Errorf("join room: %w", ErrConnClosed)
+	}
+
+	return nil
+}
+
+// Leave 离开房间，房间没有成员时销毁
+func (r *rooms) Leave(room string, conn Connection) {
+	r.lock.Lock()
+	destroyed := r.leave(room, conn.ID())
+	r.lock.Unlock()
+
+	r.destroyed(destroyed)
+}
+
+// LeaveAll 离开连接加入的所有房间，连接关闭时自动调用
+func (r *rooms) LeaveAll(conn Connection) {
+	r.lock.Lock()
+	var destroyed []string
+	for room := range r.joined[conn.ID()] {
+		destroyed = append(destroyed, r.leave(room, conn.ID())...)
+	}
+	r.lock.Unlock()
+
+	r.destroyed(destroyed)
+}
+
+// leave 从房间中移除连接，返回被销毁的房间，调用时需持有锁
+func (r *rooms) leave(room string, id uint64) []string {
+	members, ok := r.rooms[room]
+	if !ok {
+		return nil
+	}
+	if _, ok = members[id]; !ok {
+		return nil
+	}
+
+	delete(members, id)
+	delete(r.joined[id], room)
+	if len(r.joined[id]) == 0 {
+		delete(r.joined, id)
+	}
+	log.Println(fmt.Sprintf("[ ROOMS ] connection %d leave room %s", id, room))
+
+	if len(members) > 0 {
+		return nil
+	}
+	delete(r.rooms, room)
+	return []string{room}
+}
+
+// destroyed 房间销毁后的回调，不持有锁以便回调中操作房间
+func (r *rooms) destroyed(names []string) {
+	for _, room := range names {
+		log.Println(fmt.Sprintf("[ ROOMS ] room %s destroyed", room))
+		if r.onDestroy != nil {
+			r.onDestroy(room)
+		}
+	}
+}
+
+// Members 获取房间成员
+func (r *rooms) Members(room string) []Connection {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	members := make([]Connection, 0, len(r.rooms[room]))
+	for _, conn := range r.rooms[room] {
+		members = append(members, conn)
+	}
+	return members
+}
+
+// Broadcast 向房间所有成员发送数据，返回发送失败的连接 id 及原因
+func (r *rooms) Broadcast(room string, protocol uint32, data []byte) (map[uint64]error, error) {
+	return r.mgr.send(r.Members(room), nil, protocol, data)
+}
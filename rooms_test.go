@@ -0,0 +1,99 @@
+package orbit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRooms(t *testing.T) {
+	var created, destroyed []string
+	m := newManager(options{
+		onRoomCreate:  func(room string) { created = append(created, room) },
+		onRoomDestroy: func(room string) { destroyed = append(destroyed, room) },
+	})
+	rs := m.Rooms()
+
+	a := &mockConn{id: nextConnID(), addr: "10.0.0.1:1"}
+	b := &mockConn{id: nextConnID(), addr: "10.0.0.2:1"}
+	m.Add(a)
+	m.Add(b)
+
+	assert.NoError(t, rs.Join("lobby", a))
+	assert.NoError(t, rs.Join("lobby", b))
+	assert.NoError(t, rs.Join("match", a))
+	assert.Equal(t, []string{"lobby", "match"}, created)
+	assert.Len(t, rs.Members("lobby"), 2)
+
+	// 不在连接管理中的连接不能加入
+	assert.Error(t, rs.Join("lobby", &mockConn{id: nextConnID()}))
+
+	rs.Leave("lobby", b)
+	assert.Equal(t, []Connection{a}, rs.Members("lobby"))
+	assert.Empty(t, destroyed)
+
+	// 连接删除时离开所有房间，最后一个成员离开后房间销毁
+	m.Del(a)
+	assert.Empty(t, rs.Members("lobby"))
+	assert.Empty(t, rs.Members("match"))
+	assert.ElementsMatch(t, []string{"lobby", "match"}, destroyed)
+}
+
+func TestRoomsBroadcast(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Rooms().Join(string(ctx.RawData()), ctx.Connection())
+		ctx.Write(nil)
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11125), WithSequence(), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	received := make(chan string, 8)
+	join := func(room string) Client {
+		cr := Setup()
+		cr.Handle(2, func(ctx *Context) {
+			received <- room + ": " + string(ctx.RawData())
+		})
+		c, err := Dial("127.0.0.1:11125", WithSequence(), WithRouter(cr))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err = c.Call(ctx, 1, []byte(room)); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	red := join("red")
+	defer red.Close()
+	blue := join("blue")
+	defer blue.Close()
+
+	rs := srv.Manager().Rooms()
+	failed, err := rs.Broadcast("red", 2, []byte("hello"))
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	select {
+	case data := <-received:
+		assert.Equal(t, "red: hello", data)
+	case <-time.After(time.Second):
+		t.Fatal("room broadcast not received")
+	}
+	select {
+	case data := <-received:
+		t.Errorf("unexpected message: %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 连接关闭后自动离开房间
+	red.Close()
+	assert.Eventually(t, func() bool { return len(rs.Members("red")) == 0 }, time.Second, 10*time.Millisecond)
+}
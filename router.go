@@ -1,8 +1,18 @@
 package orbit
 
 import (
+	"encoding/binary"
 	"fmt"
 	"log"
+	"math"
+	"sync/atomic"
+)
+
+const (
+	// ProtocolNoRoute 保留协议，未注册协议的错误响应，数据为请求的协议号
+	ProtocolNoRoute uint32 = math.MaxUint32
+	// ProtocolError 保留协议，处理出错时的错误响应，数据为错误信息
+	ProtocolError uint32 = math.MaxUint32 - 3
 )
 
 // HandlerFunc 执行方法
@@ -11,40 +21,124 @@ type HandlerFunc func(ctx *Context)
 // HandlersChain 执行方法切片
 type HandlersChain []HandlerFunc
 
+// RouterGroup 路由分组接口
+type RouterGroup interface {
+	Use(middleware ...HandlerFunc)
+	Handle(protocol uint32, handlers ...HandlerFunc)
+	Group(start, end uint32, middleware ...HandlerFunc) RouterGroup
+}
+
 // Router 路由接口
 type Router interface {
-	Handle(protocol uint32, handler HandlerFunc)
-	exec(ctx *Context)
+	RouterGroup
+	NoRoute(handlers ...HandlerFunc)
+	NoRouteCount() uint64
+	exec(ctx *Context) bool
 }
 
 // router 路由结构体
 type router struct {
-	apis map[uint32]HandlerFunc
+	middleware HandlersChain
+	apis       map[uint32]HandlersChain
+
+	noRoute      HandlersChain
+	noRouteCount uint64
 }
 
 // Setup 路由初始化
 func Setup() Router {
 	log.Println(fmt.Sprintf("[ ROUTER ] router init"))
 	return &router{
-		apis: make(map[uint32]HandlerFunc),
+		apis: make(map[uint32]HandlersChain),
 	}
 }
 
-// Handle 添加处理句柄
-func (r *router) Handle(protocol uint32, handler HandlerFunc) {
+// Use 添加全局中间件，只对之后注册的协议生效
+func (r *router) Use(middleware ...HandlerFunc) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Handle 添加处理句柄，handlers 按顺序组成执行链
+func (r *router) Handle(protocol uint32, handlers ...HandlerFunc) {
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("protocol %d has no handler", protocol))
+	}
+	r.add(protocol, r.combine(handlers))
+}
+
+// Group 创建协议区间为 [start, end] 的路由分组
+func (r *router) Group(start, end uint32, middleware ...HandlerFunc) RouterGroup {
+	return newGroup(r, 0, math.MaxUint32, start, end, middleware)
+}
+
+// add 注册协议的执行链
+func (r *router) add(protocol uint32, chain HandlersChain) {
 	if _, ok := r.apis[protocol]; ok {
 		panic(fmt.Sprintf("repeated protocol: %d", protocol))
 	}
-	r.apis[protocol] = handler
+	r.apis[protocol] = chain
 
 	log.Println(fmt.Sprintf("[ ROUTER ] add protocol %d", protocol))
 }
 
-// exec 执行
-func (r *router) exec(ctx *Context) {
-	handler, ok := r.apis[ctx.Protocol()]
+// NoRoute 设置未注册协议的处理句柄
+func (r *router) NoRoute(handlers ...HandlerFunc) {
+	r.noRoute = r.combine(handlers)
+}
+
+// NoRouteCount 获取未注册协议的请求总数
+func (r *router) NoRouteCount() uint64 {
+	return atomic.LoadUint64(&r.noRouteCount)
+}
+
+// combine 依次合并全局中间件和处理句柄
+func (r *router) combine(chains ...HandlersChain) HandlersChain {
+	size := len(r.middleware)
+	for _, handlers := range chains {
+		size += len(handlers)
+	}
+	if size >= int(abortIndex) {
+		panic(fmt.Sprintf("too many handlers: %d", size))
+	}
+
+	chain := make(HandlersChain, 0, size)
+	chain = append(chain, r.middleware...)
+	for _, handlers := range chains {
+		chain = append(chain, handlers...)
+	}
+	return chain
+}
+
+// exec 执行，协议未注册时返回 false
+func (r *router) exec(ctx *Context) bool {
+	handlers, ok := r.apis[ctx.Protocol()]
 	if !ok {
-		return
+		atomic.AddUint64(&r.noRouteCount, 1)
+		log.Println(fmt.Sprintf("[ ROUTER ] remote addr %s protocol %d not found", ctx.RemoteAddr(), ctx.Protocol()))
+
+		handlers = r.noRoute
 	}
-	handler(ctx)
+
+	ctx.handlers = handlers
+	ctx.index = -1
+	ctx.Next()
+
+	return ok
+}
+
+// ReplyNoRoute 回复未注册协议的错误响应，可作为 NoRoute 的处理句柄
+func ReplyNoRoute(ctx *Context) {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, ctx.Protocol())
+
+	msg := NewMessagePacket(ProtocolNoRoute, data)
+	msg.SetSeq(ctx.Seq())
+	ctx.conn.SendMessage(msg)
+}
+
+// ReplyError 回复处理出错的错误响应，携带请求的序列号，Client.Call 收到后返回 ErrRemote
+func ReplyError(ctx *Context, err error) error {
+	msg := NewMessagePacket(ProtocolError, []byte(err.Error()))
+	msg.SetSeq(ctx.Seq())
+	return ctx.conn.SendMessage(msg)
 }
@@ -0,0 +1,119 @@
+package orbit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterMiddleware(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	r.Use(func(ctx *Context) {
+		trace = append(trace, "global before")
+		ctx.Next()
+		trace = append(trace, "global after")
+	})
+	r.Handle(1, func(ctx *Context) {
+		trace = append(trace, "route")
+		ctx.Next()
+	}, func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	r.exec(&Context{protocol: 1})
+	assert.Equal(t, []string{"global before", "route", "handler", "global after"}, trace)
+}
+
+func TestRouterAbort(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	r.Use(func(ctx *Context) {
+		trace = append(trace, "auth")
+		ctx.Abort()
+	})
+	r.Handle(1, func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	ctx := &Context{protocol: 1}
+	r.exec(ctx)
+	assert.True(t, ctx.IsAborted())
+	assert.Equal(t, []string{"auth"}, trace)
+}
+
+func TestRouterUseAfterHandle(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+	r.Use(func(ctx *Context) {
+		trace = append(trace, "middleware")
+	})
+
+	r.exec(&Context{protocol: 1})
+	assert.Equal(t, []string{"handler"}, trace)
+}
+
+func TestRouterHandlePanic(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {})
+
+	assert.Panics(t, func() { r.Handle(1, func(ctx *Context) {}) })
+	assert.Panics(t, func() { r.Handle(2) })
+}
+
+func TestRouterNoRoute(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	r.Use(func(ctx *Context) {
+		trace = append(trace, "middleware")
+	})
+	r.NoRoute(func(ctx *Context) {
+		trace = append(trace, "no route")
+	})
+
+	assert.False(t, r.exec(&Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}}))
+	assert.False(t, r.exec(&Context{protocol: 2, conn: &mockConn{addr: "127.0.0.1:1"}}))
+	assert.Equal(t, []string{"middleware", "no route", "middleware", "no route"}, trace)
+	assert.Equal(t, uint64(2), r.NoRouteCount())
+}
+
+func TestReplyNoRoute(t *testing.T) {
+	r := Setup()
+	r.NoRoute(ReplyNoRoute)
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11117), WithSequence(), WithMaxNoRoute(2), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	c, err := Dial("127.0.0.1:11117", WithSequence())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = c.Call(ctx, 100, nil)
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.EqualError(t, err, "protocol not found: 100")
+
+	// 达到上限后服务端断开连接
+	_, err = c.Call(ctx, 101, nil)
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		_, e := c.Call(ctx, 102, nil)
+		return e != nil && e != context.DeadlineExceeded
+	}, time.Second, 50*time.Millisecond)
+	assert.Equal(t, uint64(2), r.NoRouteCount())
+}
@@ -0,0 +1,90 @@
+package orbit
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// SendPolicy 发送队列已满时的处理策略
+type SendPolicy int
+
+const (
+	// SendBlock 阻塞等待队列空闲，直到 ctx 结束或连接关闭，Send 使用 WithSendTimeout 设置的超时
+	SendBlock SendPolicy = iota
+	// SendDropNewest 丢弃当前要发送的消息并返回错误
+	SendDropNewest
+	// SendDropOldest 丢弃队列中最早的消息，当前消息加入队列
+	SendDropOldest
+	// SendDisconnect 认为对端接收过慢，关闭连接
+	SendDisconnect
+)
+
+// String 发送策略的描述
+func (p SendPolicy) String() string {
+	switch p {
+	case SendBlock:
+		return "block"
+	case SendDropNewest:
+		return "drop newest"
+	case SendDropOldest:
+		return "drop oldest"
+	case SendDisconnect:
+		return "disconnect"
+	}
+	return fmt.Sprintf("unknown(%d)", int(p))
+}
+
+// enqueue 将已封包的数据加入发送队列，队列已满时按发送策略处理
+func (c *connection) enqueue(ctx context.Context, buff []byte) error {
+	if c.closed() {
+		return ErrConnClosed
+	}
+
+	// 队列未满直接加入
+	select {
+	case c.msgCh <- buff:
+		return nil
+	default:
+	}
+
+	switch c.sendPolicy {
+	case SendDropNewest:
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s send queue is full, drop newest msg", c.RemoteAddr()))
+		return ErrQueueFull
+	case SendDropOldest:
+		for {
+			select {
+			case c.msgCh <- buff:
+				return nil
+			case <-c.ctx.Done():
+				return ErrConnClosed
+			default:
+			}
+
+			select {
+			case <-c.msgCh:
+				log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s send queue is full, drop oldest msg", c.RemoteAddr()))
+			default:
+			}
+		}
+	case SendDisconnect:
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s send queue is full, disconnect slow consumer", c.RemoteAddr()))
+		c.closeWith(newCloseError(CloseSlowConsumer, nil))
+		return fmt.Errorf("%w, disconnect slow consumer", ErrQueueFull)
+	}
+
+	select {
+	case c.msgCh <- buff:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return ErrConnClosed
+	}
+}
+
+// SendQueueLen 获取发送队列中等待写出的消息数量，持续接近队列长度说明对端接收过慢
+func (c *connection) SendQueueLen() int {
+	return len(c.msgCh)
+}
@@ -0,0 +1,246 @@
+package orbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newQueueConn 创建未开始写出的连接，发送队列只能容纳两条消息
+func newQueueConn(t *testing.T, policy SendPolicy) (*connection, net.Conn) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	o := options{sendQueue: 2, sendPolicy: policy, sendTimeout: 10 * time.Millisecond}
+	c := newConnection(server, newManager(o), nil, o)
+	return c, client
+}
+
+func TestSendBlock(t *testing.T) {
+	c, _ := newQueueConn(t, SendBlock)
+	assert.NoError(t, c.Send(1, nil))
+	assert.NoError(t, c.Send(2, nil))
+	assert.Equal(t, 2, c.SendQueueLen())
+
+	// Send 使用发送超时，SendContext 使用 ctx
+	assert.Error(t, c.Send(3, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.SendContext(ctx, 3, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// 队列空闲后可以继续发送
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-c.msgCh
+	}()
+	assert.NoError(t, c.SendContext(context.Background(), 3, nil))
+	assert.Equal(t, 2, c.SendQueueLen())
+}
+
+func TestSendDropNewest(t *testing.T) {
+	c, _ := newQueueConn(t, SendDropNewest)
+	assert.NoError(t, c.Send(1, nil))
+	assert.NoError(t, c.Send(2, nil))
+	assert.Error(t, c.Send(3, nil))
+
+	dp := NewDataPacket()
+	first, _ := dp.Pack(NewMessagePacket(1, nil))
+	assert.Equal(t, first, <-c.msgCh)
+}
+
+func TestSendDropOldest(t *testing.T) {
+	c, _ := newQueueConn(t, SendDropOldest)
+	assert.NoError(t, c.Send(1, nil))
+	assert.NoError(t, c.Send(2, nil))
+	assert.NoError(t, c.Send(3, nil))
+	assert.Equal(t, 2, c.SendQueueLen())
+
+	dp := NewDataPacket()
+	second, _ := dp.Pack(NewMessagePacket(2, nil))
+	third, _ := dp.Pack(NewMessagePacket(3, nil))
+	assert.Equal(t, second, <-c.msgCh)
+	assert.Equal(t, third, <-c.msgCh)
+}
+
+func TestSendDisconnect(t *testing.T) {
+	disconnected := make(chan error, 1)
+	server, client := net.Pipe()
+	defer client.Close()
+
+	o := options{
+		sendQueue:    2,
+		sendPolicy:   SendDisconnect,
+		onDisconnect: func(conn Connection, reason error) { disconnected <- reason },
+	}
+	c := newConnection(server, newManager(o), nil, o)
+	assert.NoError(t, c.Send(1, nil))
+	assert.NoError(t, c.Send(2, nil))
+	assert.Error(t, c.Send(3, nil))
+
+	// 不读取数据的对端被关闭
+	go c.Handle()
+	select {
+	case reason := <-disconnected:
+		assert.ErrorIs(t, reason, CloseSlowConsumer)
+	case <-time.After(time.Second):
+		t.Fatal("slow consumer not disconnected")
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	o := options{sendQueue: 8, writeBatch: 10}
+	c := newConnection(server, newManager(o), nil, o)
+	defer c.Close()
+
+	// 队列中等待的数据合并写出，超过 writeBatch 后下一次写出
+	for _, data := range []string{"abcd", "efgh", "ijkl", "mn"} {
+		c.msgCh <- []byte(data)
+	}
+	assert.Equal(t, net.Buffers{[]byte("abcd"), []byte("efgh"), []byte("ijkl")}, c.batch(<-c.msgCh))
+	assert.Equal(t, net.Buffers{[]byte("mn")}, c.batch(<-c.msgCh))
+
+	// 合并的数据一次写出
+	go c.write(net.Buffers{[]byte("ab"), []byte("cd")})
+	data := make([]byte, 4)
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := io.ReadFull(client, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", string(data))
+}
+
+// countConn 统计写出次数的连接
+type countConn struct {
+	net.Conn
+	writes int32
+}
+
+func (c *countConn) Write(b []byte) (int, error) {
+	atomic.AddInt32(&c.writes, 1)
+	return c.Conn.Write(b)
+}
+
+func TestWriteBatchCopy(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	// 不支持向量写入的连接合并后一次写出
+	conn := &countConn{Conn: server}
+	o := options{sendQueue: 8, writeBatch: 1024}
+	c := newConnection(conn, newManager(o), nil, o)
+	defer c.Close()
+	assert.False(t, c.writev)
+
+	go c.write(net.Buffers{[]byte("ab"), []byte("cd"), []byte("ef")})
+	data := make([]byte, 6)
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := io.ReadFull(client, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef", string(data))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conn.writes))
+
+	// TCP 连接使用向量写入
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	tc, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Close()
+	assert.True(t, vectored(tc))
+}
+
+// BenchmarkWriteBatch 对比逐个写出与合并写出的吞吐量和写系统调用次数
+func BenchmarkWriteBatch(b *testing.B) {
+	for _, batch := range []int{0, 64 * 1024} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			benchmarkWriteBatch(b, batch)
+		})
+	}
+}
+
+func benchmarkWriteBatch(b *testing.B, batch int) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer lis.Close()
+
+	// 对端读取并丢弃所有数据
+	received := make(chan int64, 1)
+	go func() {
+		conn, e := lis.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		n, _ := io.Copy(io.Discard, conn)
+		received <- n
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	o := options{sendQueue: 1024, writeBatch: batch}
+	c := newConnection(conn, newManager(o), nil, o)
+	go c.writeProcessor()
+
+	buff, _ := NewDataPacket().Pack(NewMessagePacket(1, make([]byte, 64)))
+	b.SetBytes(int64(len(buff)))
+	b.ReportAllocs()
+
+	syscw := writeSyscalls()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = c.enqueue(context.Background(), buff); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// 等待全部写出后关闭，对端读到的长度与发送的一致
+	close(c.flush)
+	<-c.writeDone
+	b.StopTimer()
+	if syscw >= 0 {
+		b.ReportMetric(float64(writeSyscalls()-syscw)/float64(b.N), "syscw/op")
+	}
+	conn.Close()
+	if n := <-received; n != int64(b.N*len(buff)) {
+		b.Fatalf("received %d bytes, want %d", n, b.N*len(buff))
+	}
+}
+
+// writeSyscalls 读取当前进程的写系统调用次数，不支持时返回 -1
+func writeSyscalls() int64 {
+	data, err := os.ReadFile("/proc/self/io")
+	if err != nil {
+		return -1
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "syscw: ") {
+			n, _ := strconv.ParseInt(strings.TrimPrefix(line, "syscw: "), 10, 64)
+			return n
+		}
+	}
+	return -1
+}
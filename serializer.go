@@ -0,0 +1,58 @@
+package orbit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Serializer 消息内容序列化接口
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonSerializer JSON 序列化结构体
+type jsonSerializer struct{}
+
+// NewJSONSerializer JSON 序列化实例化
+func NewJSONSerializer() Serializer {
+	return &jsonSerializer{}
+}
+
+// Marshal 序列化
+func (s *jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal 反序列化
+func (s *jsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// protobufSerializer protobuf 序列化结构体
+type protobufSerializer struct{}
+
+// NewProtobufSerializer protobuf 序列化实例化
+func NewProtobufSerializer() Serializer {
+	return &protobufSerializer{}
+}
+
+// Marshal 序列化，v 必须实现 proto.Message
+func (s *protobufSerializer) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal 反序列化，v 必须实现 proto.Message
+func (s *protobufSerializer) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
@@ -0,0 +1,146 @@
+package orbit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// issue 签发测试证书，parent 为空时生成自签名 CA
+func issue(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tpl.IsCA = true
+		tpl.BasicConstraintsValid = true
+		parent, parentKey = tpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	ca, caCert := issue(t, "orbit ca", nil, nil)
+	caKey := caCert.PrivateKey.(*ecdsa.PrivateKey)
+	_, serverCert := issue(t, "orbit server", ca, caKey)
+	_, clientCert := issue(t, "orbit client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write([]byte(ctx.PeerSubject()))
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11119), WithRouter(r), WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	reply := make(chan []byte, 1)
+	cr := Setup()
+	cr.Handle(1, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+
+	c, err := Dial("127.0.0.1:11119", WithRouter(cr), WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      pool,
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// 服务端可以获取客户端证书的主题
+	assert.NoError(t, c.Send(1, nil))
+	select {
+	case data := <-reply:
+		assert.Equal(t, "CN=orbit client", string(data))
+	case <-time.After(3 * time.Second):
+		t.Error("client receive reply timeout")
+	}
+
+	// 不信任服务端证书时握手失败
+	_, err = Dial("127.0.0.1:11119", WithTLSConfig(&tls.Config{}))
+	assert.Error(t, err)
+}
+
+func TestOnConnectPeerCertificates(t *testing.T) {
+	ca, caCert := issue(t, "orbit ca", nil, nil)
+	caKey := caCert.PrivateKey.(*ecdsa.PrivateKey)
+	_, serverCert := issue(t, "orbit server", ca, caKey)
+	_, clientCert := issue(t, "orbit client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	// 连接建立的回调在握手完成后执行，可以获取客户端证书
+	subjects := make(chan string, 1)
+	srv := New(WithIP("127.0.0.1"), WithPort(11127), WithRouter(Setup()), WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}), WithOnConnect(func(conn Connection) error {
+		if certs := conn.PeerCertificates(); len(certs) > 0 {
+			subjects <- certs[0].Subject.String()
+		} else {
+			subjects <- ""
+		}
+		return nil
+	}))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	c, err := Dial("127.0.0.1:11127", WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      pool,
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	select {
+	case subject := <-subjects:
+		assert.Equal(t, "CN=orbit client", subject)
+	case <-time.After(3 * time.Second):
+		t.Error("connect callback not called")
+	}
+}
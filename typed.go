@@ -0,0 +1,44 @@
+package orbit
+
+import (
+	"fmt"
+	"log"
+)
+
+// TypedHandlerFunc 类型化的执行方法，返回 nil 响应时不回复，返回错误时回复 ProtocolError
+type TypedHandlerFunc[Req any, Resp any] func(ctx *Context, req *Req) (*Resp, error)
+
+// HandleTyped 添加类型化的处理句柄，请求数据按连接的序列化方式解码，响应编码后返回，
+// 解码或处理出错时通过 ReplyError 回复错误，等待响应的调用方不必等到超时
+func HandleTyped[Req any, Resp any](r RouterGroup, protocol uint32, handler TypedHandlerFunc[Req, Resp], middleware ...HandlerFunc) {
+	handlers := append(HandlersChain{}, middleware...)
+	r.Handle(protocol, append(handlers, func(ctx *Context) {
+		req := new(Req)
+		if err := ctx.Bind(req); err != nil {
+			log.Println(fmt.Sprintf("[ ROUTER ] remote addr %s protocol %d bind err: %e", ctx.RemoteAddr(), ctx.Protocol(), err))
+			replyError(ctx, err)
+			return
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			log.Println(fmt.Sprintf("[ ROUTER ] remote addr %s protocol %d handle err: %e", ctx.RemoteAddr(), ctx.Protocol(), err))
+			replyError(ctx, err)
+			return
+		}
+		if resp == nil {
+			return
+		}
+
+		if err = ctx.Reply(resp); err != nil {
+			log.Println(fmt.Sprintf("[ ROUTER ] remote addr %s protocol %d reply err: %e", ctx.RemoteAddr(), ctx.Protocol(), err))
+		}
+	})...)
+}
+
+// replyError 回复错误响应，发送失败时记录日志
+func replyError(ctx *Context, err error) {
+	if e := ReplyError(ctx, err); e != nil {
+		log.Println(fmt.Sprintf("[ ROUTER ] remote addr %s protocol %d reply err: %e", ctx.RemoteAddr(), ctx.Protocol(), e))
+	}
+}
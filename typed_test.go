@@ -0,0 +1,121 @@
+package orbit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type mockSendConn struct {
+	mockConn
+	sent []Message
+}
+
+func (m *mockSendConn) SendMessage(msg Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type loginReq struct {
+	Name string `json:"name"`
+}
+
+type loginResp struct {
+	Welcome string `json:"welcome"`
+}
+
+func TestHandleTypedJSON(t *testing.T) {
+	r := Setup()
+	HandleTyped(r, 1001, func(ctx *Context, req *loginReq) (*loginResp, error) {
+		return &loginResp{Welcome: "hello " + req.Name}, nil
+	})
+	HandleTyped(r, 1002, func(ctx *Context, req *loginReq) (*loginResp, error) {
+		return nil, errors.New("denied")
+	})
+
+	s := NewJSONSerializer()
+	conn := &mockSendConn{mockConn: mockConn{addr: "127.0.0.1:1"}}
+
+	r.exec(&Context{protocol: 1001, data: []byte(`{"name":"orbit"}`), conn: conn, serializer: s})
+	if assert.Len(t, conn.sent, 1) {
+		var resp loginResp
+		assert.NoError(t, s.Unmarshal(conn.sent[0].GetData(), &resp))
+		assert.Equal(t, "hello orbit", resp.Welcome)
+		assert.Equal(t, uint32(1001), conn.sent[0].GetProtocol())
+	}
+
+	// 解码失败或处理出错时回复错误，序列号与请求一致
+	r.exec(&Context{protocol: 1001, seq: 7, data: []byte(`{`), conn: conn, serializer: s})
+	r.exec(&Context{protocol: 1002, seq: 8, data: []byte(`{}`), conn: conn, serializer: s})
+	if assert.Len(t, conn.sent, 3) {
+		assert.Equal(t, ProtocolError, conn.sent[1].GetProtocol())
+		assert.Equal(t, uint32(7), conn.sent[1].GetSeq())
+		assert.Equal(t, ProtocolError, conn.sent[2].GetProtocol())
+		assert.Equal(t, uint32(8), conn.sent[2].GetSeq())
+		assert.Equal(t, "denied", string(conn.sent[2].GetData()))
+	}
+}
+
+func TestHandleTypedCallError(t *testing.T) {
+	r := Setup()
+	HandleTyped(r, 1, func(ctx *Context, req *loginReq) (*loginResp, error) {
+		return nil, errors.New("denied")
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11129), WithSequence(), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	c, err := Dial("127.0.0.1:11129", WithSequence())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// 处理出错时调用立即返回，不必等到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = c.Call(ctx, 1, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrRemote)
+	assert.Contains(t, err.Error(), "denied")
+
+	_, err = c.Call(ctx, 1, []byte(`{`))
+	assert.ErrorIs(t, err, ErrRemote)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestHandleTypedProtobuf(t *testing.T) {
+	var trace []string
+
+	r := Setup()
+	HandleTyped(r, 1, func(ctx *Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+		trace = append(trace, "handler")
+		return wrapperspb.String("echo: " + req.GetValue()), nil
+	}, func(ctx *Context) {
+		trace = append(trace, "middleware")
+	})
+
+	s := NewProtobufSerializer()
+	data, err := s.Marshal(wrapperspb.String("hi"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	conn := &mockSendConn{mockConn: mockConn{addr: "127.0.0.1:1"}}
+	r.exec(&Context{protocol: 1, data: data, conn: conn, serializer: s})
+	assert.Equal(t, []string{"middleware", "handler"}, trace)
+	if assert.Len(t, conn.sent, 1) {
+		resp := &wrapperspb.StringValue{}
+		assert.NoError(t, s.Unmarshal(conn.sent[0].GetData(), resp))
+		assert.Equal(t, "echo: hi", resp.GetValue())
+	}
+
+	// 非 proto.Message 无法序列化
+	_, err = s.Marshal(&loginReq{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,287 @@
+package orbit
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// udpHeadLength UDP 数据报头部长度，只包含协议号，数据长度即数据报剩余长度
+const udpHeadLength = 4
+
+// maxDatagramSize UDP 数据报的最大长度
+const maxDatagramSize = 64 * 1024
+
+// udpSession UDP 伪会话结构体，每个远程地址对应一个会话
+type udpSession struct {
+	id      uint64
+	pc      net.PacketConn
+	addr    net.Addr
+	manager Manager
+	worker  Worker
+	properties
+
+	size       uint32
+	serializer Serializer
+	idle       time.Duration
+	timer      *time.Timer
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	draining int32
+	tasks    sync.WaitGroup
+	noRoute  uint32
+
+	reason       *CloseError
+	reasonOnce   sync.Once
+	onConnect    func(conn Connection) error
+	onDisconnect func(conn Connection, reason error)
+}
+
+// newUDPSession 创建 UDP 会话，通过 connect 完成建立后才加入连接管理
+func newUDPSession(pc net.PacketConn, addr net.Addr, manager Manager, worker Worker, o options) *udpSession {
+	s := &udpSession{
+		id:      nextConnID(),
+		pc:      pc,
+		addr:    addr,
+		manager: manager,
+		worker:  worker,
+
+		size:       o.packet,
+		serializer: o.serializer,
+		idle:       o.sessionIdle,
+
+		onConnect:    o.onConnect,
+		onDisconnect: o.onDisconnect,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	return s
+}
+
+// connect 执行连接建立的回调，通过后加入连接管理，返回错误时拒绝会话
+func (s *udpSession) connect() error {
+	if s.onConnect != nil {
+		if err := s.onConnect(s); err != nil {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s rejected: %e", s.RemoteAddr(), err))
+			s.closeWith(newCloseError(CloseKicked, err))
+			s.manager.Del(s)
+			return err
+		}
+	}
+	s.manager.Add(s)
+
+	// 超过空闲时间没有收到数据报则关闭会话，小于等于 0 时不检测
+	if s.idle > 0 {
+		s.timer = time.AfterFunc(s.idle, func() {
+			log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s session idle timeout", s.RemoteAddr()))
+			s.closeWith(newCloseError(CloseIdleTimeout, nil))
+		})
+	}
+
+	return nil
+}
+
+// ID 获取会话 id
+func (s *udpSession) ID() uint64 {
+	return s.id
+}
+
+// Manager 获取会话所属的连接管理
+func (s *udpSession) Manager() Manager {
+	return s.manager
+}
+
+// Handle 阻塞等待会话关闭
+func (s *udpSession) Handle() {
+	<-s.ctx.Done()
+	s.finalizer()
+}
+
+// receive 处理收到的数据报
+func (s *udpSession) receive(datagram []byte) {
+	if s.ctx.Err() != nil || atomic.LoadInt32(&s.draining) == 1 {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Reset(s.idle)
+	}
+
+	if len(datagram) < udpHeadLength {
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s datagram too short: %d", s.RemoteAddr(), len(datagram)))
+		return
+	}
+	if s.size > 0 && uint32(len(datagram)-udpHeadLength) > s.size {
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s received too large message", s.RemoteAddr()))
+		return
+	}
+
+	// 读取缓冲区会被复用，需要复制数据
+	data := make([]byte, len(datagram)-udpHeadLength)
+	copy(data, datagram[udpHeadLength:])
+
+	// 将消息交给工作池的任务队列中进行处理处理
+	s.tasks.Add(1)
+	s.worker.JoinTaskQueue(&Context{
+		protocol: binary.LittleEndian.Uint32(datagram),
+		data:     data,
+		conn:     s,
+		tasks:    &s.tasks,
+
+		serializer: s.serializer,
+	})
+}
+
+// Send 发送数据
+func (s *udpSession) Send(protocol uint32, data []byte) error {
+	return s.SendMessage(NewMessagePacket(protocol, data))
+}
+
+// SendContext 发送数据，数据报直接写出，不经过发送队列
+func (s *udpSession) SendContext(ctx context.Context, protocol uint32, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Send(protocol, data)
+}
+
+// SendQueueLen 数据报直接写出，没有发送队列
+func (s *udpSession) SendQueueLen() int {
+	return 0
+}
+
+// SendMessage 发送消息包，数据报不携带长度和序列号
+func (s *udpSession) SendMessage(msg Message) error {
+	if s.ctx.Err() != nil {
+		return ErrConnClosed
+	}
+
+	datagram := make([]byte, udpHeadLength+len(msg.GetData()))
+	binary.LittleEndian.PutUint32(datagram, msg.GetProtocol())
+	copy(datagram[udpHeadLength:], msg.GetData())
+
+	_, err := s.pc.WriteTo(datagram, s.addr)
+	return err
+}
+
+// Close 关闭会话
+func (s *udpSession) Close() {
+	s.closeWith(newCloseError(CloseKicked, nil))
+}
+
+// closeWith 按指定原因关闭会话，已关闭时保留第一次的原因
+func (s *udpSession) closeWith(reason *CloseError) {
+	s.reasonOnce.Do(func() {
+		s.reason = reason
+	})
+	s.cancel()
+}
+
+// Shutdown 优雅关闭会话，停止接收数据报并等待已收到的消息处理完成后关闭
+func (s *udpSession) Shutdown(ctx context.Context) error {
+	defer s.closeWith(newCloseError(CloseShutdown, nil))
+	atomic.StoreInt32(&s.draining, 1)
+
+	tasks := make(chan struct{})
+	go func() {
+		s.tasks.Wait()
+		close(tasks)
+	}()
+	select {
+	case <-tasks:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RemoteAddr 获取远程客户端地址
+func (s *udpSession) RemoteAddr() string {
+	return s.addr.String()
+}
+
+// PeerCertificates UDP 会话没有证书
+func (s *udpSession) PeerCertificates() []*x509.Certificate {
+	return nil
+}
+
+// incNoRoute 请求未注册协议的次数加一
+func (s *udpSession) incNoRoute() uint32 {
+	return atomic.AddUint32(&s.noRoute, 1)
+}
+
+// finalizer 会话关闭后的处理
+func (s *udpSession) finalizer() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.manager.Del(s)
+	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s session closed: %s", s.RemoteAddr(), s.reason.Reason))
+
+	if s.onDisconnect != nil {
+		s.onDisconnect(s, s.reason)
+	}
+}
+
+// serveUDP 在数据报连接上提供服务，阻塞直到连接关闭
+func (l *listener) serveUDP(pc net.PacketConn) error {
+	l.pc = pc
+
+	// 启用工作池机制
+	l.work.Start()
+
+	// 多读取一个字节用于判断数据报是否超过允许值，未限制长度时按数据报的最大长度读取
+	size := maxDatagramSize
+	if l.opts.packet > 0 {
+		size = udpHeadLength + int(l.opts.packet) + 1
+	}
+	buff := make([]byte, size)
+	for {
+		n, addr, err := pc.ReadFrom(buff)
+		if err != nil {
+			// 如果 listener 已关闭或正在优雅停止
+			if errors.Is(err, net.ErrClosed) || atomic.LoadInt32(&l.draining) == 1 {
+				return nil
+			}
+			log.Println(fmt.Sprintf("[ LISTENER ] read datagram err: %e", err))
+			continue
+		}
+
+		s, err := l.session(pc, addr)
+		if err != nil {
+			continue
+		}
+		s.receive(buff[:n])
+	}
+}
+
+// session 获取远程地址对应的会话，不存在时创建
+func (l *listener) session(pc net.PacketConn, addr net.Addr) (*udpSession, error) {
+	if conn, err := l.mgr.Get(addr.String()); err == nil {
+		if s, ok := conn.(*udpSession); ok && s.ctx.Err() == nil {
+			return s, nil
+		}
+	}
+
+	// 如果当前会话数量超过最大连接数，则忽略新的会话
+	s := newUDPSession(pc, addr, l.mgr, l.work, l.opts)
+	if !l.mgr.reserve(s, l.opts.conns) {
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s because limit connect is ignored", addr.String()))
+		return nil, ErrTooManyConns
+	}
+	log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s session established", addr.String()))
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	go s.Handle()
+
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package orbit
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUDP(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(append([]byte("echo: "), ctx.RawData()...))
+	})
+
+	srv := New(WithNetwork("udp"), WithIP("127.0.0.1"), WithPort(11122), WithSessionIdleTimeout(300*time.Millisecond), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("udp", "127.0.0.1:11122")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 每个数据报是一个消息：协议号 + 数据
+	datagram := make([]byte, 4, 9)
+	binary.LittleEndian.PutUint32(datagram, 1)
+	datagram = append(datagram, []byte("hello")...)
+
+	buff := make([]byte, 64)
+	for i := 0; i < 2; i++ {
+		if _, err = conn.Write(datagram); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, e := conn.Read(buff)
+		if e != nil {
+			t.Fatal(e)
+		}
+		assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(buff))
+		assert.Equal(t, "echo: hello", string(buff[4:n]))
+	}
+
+	// 同一个远程地址只有一个会话，空闲超时后移除
+	mgr := srv.(*listener).mgr
+	assert.Equal(t, 1, mgr.Len())
+	assert.Eventually(t, func() bool { return mgr.Len() == 0 }, time.Second, 50*time.Millisecond)
+}
+
+func TestDialUDP(t *testing.T) {
+	_, err := Dial("127.0.0.1:11122", WithNetwork("udp"))
+	assert.Error(t, err)
+}
+
+func TestUDPUnlimitedPacket(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+
+	// 不限制长度时完整读取数据报
+	srv := New(WithNetwork("udp"), WithIP("127.0.0.1"), WithPort(11126), WithMaxMessagePacketSize(0), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("udp", "127.0.0.1:11126")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	datagram := make([]byte, 4, 4+8192)
+	binary.LittleEndian.PutUint32(datagram, 1)
+	for i := 0; i < 8192; i++ {
+		datagram = append(datagram, byte(i))
+	}
+	if _, err = conn.Write(datagram); err != nil {
+		t.Fatal(err)
+	}
+
+	buff := make([]byte, maxDatagramSize)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, datagram, buff[:n])
+}
+
+func TestUDPSessionNoIdleTimeout(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	// 空闲超时小于等于 0 时会话不会因空闲关闭
+	o := options{sessionIdle: 0}
+	mgr := newManager(o)
+	s := newUDPSession(pc, pc.LocalAddr(), mgr, nil, o)
+	assert.NoError(t, s.connect())
+	s.receive([]byte{1})
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, s.ctx.Err())
+	assert.Equal(t, 1, mgr.Len())
+
+	s.Close()
+	s.Handle()
+	assert.Equal(t, 0, mgr.Len())
+}
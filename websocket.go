@@ -0,0 +1,116 @@
+package orbit
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn 将 WebSocket 连接适配为 net.Conn，每个二进制消息承载一个完整的数据包
+type wsConn struct {
+	*websocket.Conn
+	reader io.Reader
+}
+
+// newWSConn 创建 WebSocket 适配连接
+func newWSConn(ws *websocket.Conn) net.Conn {
+	return &wsConn{Conn: ws}
+}
+
+// Read 按字节流读取二进制消息，忽略其他类型的消息
+func (c *wsConn) Read(b []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			mt, r, err := c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			if mt != websocket.BinaryMessage {
+				continue
+			}
+			c.reader = r
+		}
+
+		n, err := c.reader.Read(b)
+		if err == io.EOF {
+			// 当前消息读取完毕，继续读取下一条消息
+			c.reader = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Write 每次写入作为一条二进制消息发送
+func (c *wsConn) Write(b []byte) (int, error) {
+	if err := c.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// SetDeadline 设置读写超时
+func (c *wsConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// ServeHTTP 将 HTTP 请求升级为 WebSocket 连接并处理，阻塞直到连接关闭
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: l.opts.checkOrigin}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(fmt.Sprintf("[ CONNECT ] remote addr %s websocket upgrade err: %e", r.RemoteAddr, err))
+		return
+	}
+
+	// 启用工作池机制
+	l.work.Start()
+
+	l.serve(newWSConn(ws))
+}
+
+// serveWebSocket 在监听器上提供 WebSocket 服务
+func (l *listener) serveWebSocket(lis net.Listener) error {
+	if l.opts.tls != nil {
+		lis = tls.NewListener(lis, l.opts.tls)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(l.opts.websocket, l)
+	log.Println(fmt.Sprintf("[ LISTENER ] serve websocket on %s", l.opts.websocket))
+
+	// 如果 listener 已关闭
+	if err := http.Serve(lis, mux); !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// DialWebSocket 通过 WebSocket 连接服务端，url 形如 ws://127.0.0.1:62817/ws
+func DialWebSocket(url string, opts ...Option) (Client, error) {
+	o := clientOptions(opts)
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = o.tls
+
+	ws, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Println(fmt.Sprintf("[ CLIENT ] dial to %s", url))
+
+	return newClient(newWSConn(ws), o), nil
+}
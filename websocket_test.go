@@ -0,0 +1,84 @@
+package orbit
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// echoReply 发送消息并等待客户端路由收到回复
+func echoReply(t *testing.T, dial func(r Router) (Client, error), data string) {
+	reply := make(chan []byte, 1)
+	cr := Setup()
+	cr.Handle(1, func(ctx *Context) {
+		reply <- ctx.RawData()
+	})
+
+	c, err := dial(cr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, c.Send(1, []byte(data)))
+	select {
+	case resp := <-reply:
+		assert.Equal(t, data, string(resp))
+	case <-time.After(time.Second):
+		t.Errorf("%s receive reply timeout", data)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+
+	// 同一个服务同时处理 TCP 和 WebSocket 连接
+	srv := New(WithIP("127.0.0.1"), WithPort(11120), WithRouter(r))
+	go srv.On()
+	defer srv.Off()
+
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	echoReply(t, func(r Router) (Client, error) {
+		return Dial("127.0.0.1:11120", WithRouter(r))
+	}, "tcp")
+	echoReply(t, func(r Router) (Client, error) {
+		return DialWebSocket("ws"+strings.TrimPrefix(hs.URL, "http"), WithRouter(r))
+	}, "websocket")
+}
+
+func TestWithWebSocket(t *testing.T) {
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		ctx.Write(ctx.RawData())
+	})
+
+	srv := New(WithIP("127.0.0.1"), WithPort(11121), WithWebSocket("/ws"), WithRouter(r))
+	go srv.On()
+	time.Sleep(100 * time.Millisecond)
+
+	echoReply(t, func(r Router) (Client, error) {
+		return DialWebSocket("ws://127.0.0.1:11121/ws", WithRouter(r))
+	}, "hello")
+
+	// 多个数据包在同一连接上连续发送
+	echoReply(t, func(r Router) (Client, error) {
+		c, err := DialWebSocket("ws://127.0.0.1:11121/ws", WithRouter(r))
+		if err == nil {
+			err = c.Send(2, []byte("unknown"))
+		}
+		return c, err
+	}, "again")
+
+	assert.NoError(t, srv.Off())
+	_, err := DialWebSocket("ws://127.0.0.1:11121/ws")
+	assert.Error(t, err)
+}
@@ -2,14 +2,16 @@ package orbit
 
 import (
 	"fmt"
-	"hash/fnv"
 	"log"
+	"runtime/debug"
+	"sync"
 )
 
 // Worker 接口实现
 type Worker interface {
 	GetWorkerPoolSize() int
-	UseWorkerPool()
+	Start()
+	Stop()
 	UseSingleWorker(wid int, taskQueue chan *Context)
 	JoinTaskQueue(ctx *Context)
 }
@@ -20,6 +22,34 @@ type worker struct {
 	taskLen   int
 	taskQueue []chan *Context
 	router    Router
+
+	onStart func(wid int)
+	onStop  func(wid int)
+
+	onPanic      PanicHandler
+	closeOnPanic bool
+	maxNoRoute   uint32
+
+	lock    sync.RWMutex
+	running bool
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newWorker 创建工作池
+func newWorker(o options) *worker {
+	return &worker{
+		poolSize:  o.pool,
+		taskLen:   o.tasks,
+		taskQueue: make([]chan *Context, o.pool),
+		router:    o.router,
+		onStart:   o.onWorkerStart,
+		onStop:    o.onWorkerStop,
+
+		onPanic:      o.onPanic,
+		closeOnPanic: o.closeOnPanic,
+		maxNoRoute:   o.maxNoRoute,
+	}
 }
 
 // GetWorkerPoolSize 获取工作池大小
@@ -27,32 +57,140 @@ func (w *worker) GetWorkerPoolSize() int {
 	return w.poolSize
 }
 
-// UseWorkerPool 使用 worker pool
-func (w *worker) UseWorkerPool() {
+// Start 启动工作池，重复调用无副作用
+func (w *worker) Start() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.running {
+		return
+	}
+	w.running = true
+	w.quit = make(chan struct{})
+
 	log.Println(fmt.Sprintf("[ WORKER ] worker pool init, size: %d, task length: %d", w.poolSize, w.taskLen))
 	for i := 0; i < w.poolSize; i++ {
 		w.taskQueue[i] = make(chan *Context, w.taskLen)
-		go w.UseSingleWorker(i, w.taskQueue[i])
+		w.wg.Add(1)
+		go w.run(i, w.taskQueue[i], w.quit)
 	}
 }
 
-// UseSingleWorker 使用单个 worker
+// Stop 停止工作池并等待所有 worker 退出，队列中未处理的任务会被丢弃并释放
+func (w *worker) Stop() {
+	w.lock.Lock()
+	if !w.running {
+		w.lock.Unlock()
+		return
+	}
+	w.running = false
+	close(w.quit)
+	w.lock.Unlock()
+
+	w.wg.Wait()
+	log.Println(fmt.Sprintf("[ WORKER ] worker pool stopped"))
+}
+
+// UseSingleWorker 使用单个 worker，在当前工作池停止时退出，工作池未启动时直接返回
 func (w *worker) UseSingleWorker(wid int, taskQueue chan *Context) {
+	w.lock.Lock()
+	if !w.running {
+		w.lock.Unlock()
+		log.Println(fmt.Sprintf("[ WORKER ] worker pool is not running, worker %d not started", wid))
+		return
+	}
+	// 持有锁时加入等待，Stop 会等待该 worker 退出
+	w.wg.Add(1)
+	quit := w.quit
+	w.lock.Unlock()
+
+	w.run(wid, taskQueue, quit)
+}
+
+// run 运行单个 worker，quit 由启动时传入，避免重新启动后读到新的退出信号
+func (w *worker) run(wid int, taskQueue chan *Context, quit chan struct{}) {
+	defer w.wg.Done()
+
+	if w.onStart != nil {
+		w.onStart(wid)
+	}
+	if w.onStop != nil {
+		defer w.onStop(wid)
+	}
+
 	log.Println(fmt.Sprintf("[ WORKER ] worker %d is ready", wid))
+	for {
+		select {
+		case <-quit:
+			w.discard(wid, taskQueue)
+			log.Println(fmt.Sprintf("[ WORKER ] worker %d exit", wid))
+			return
+		case task := <-taskQueue:
+			w.exec(task)
+		}
+	}
+}
+
+// discard 丢弃队列中未处理的任务，释放任务持有的缓冲区和连接的等待计数
+func (w *worker) discard(wid int, taskQueue chan *Context) {
 	for {
 		select {
 		case task := <-taskQueue:
-			w.router.exec(task)
+			log.Println(fmt.Sprintf("[ WORKER ] worker %d stopped, drop task from %s, protocol: %d", wid, task.RemoteAddr(), task.Protocol()))
+			task.done()
+		default:
+			return
+		}
+	}
+}
+
+// exec 执行任务，恢复处理方法中的 panic 以保证 worker 继续服务
+func (w *worker) exec(task *Context) {
+	defer task.done()
+	defer func() {
+		if err := recover(); err != nil {
+			stack := debug.Stack()
+			log.Println(fmt.Sprintf("[ WORKER ] remote addr %s protocol %d panic: %v\n%s", task.RemoteAddr(), task.Protocol(), err, stack))
+
+			if w.onPanic != nil {
+				w.onPanic(task, err, stack)
+			}
+			// 只关闭引发 panic 的连接
+			if w.closeOnPanic {
+				task.conn.Close()
+			}
+		}
+	}()
+
+	if !w.router.exec(task) && w.maxNoRoute > 0 {
+		// 请求未注册协议的次数达到上限时断开连接
+		if c, ok := task.conn.(noRouteCounter); ok && c.incNoRoute() >= w.maxNoRoute {
+			log.Println(fmt.Sprintf("[ WORKER ] remote addr %s requests too many unknown protocols", task.RemoteAddr()))
+			task.conn.Close()
 		}
 	}
 }
 
-// JoinTaskQueue 加入任务队列
+// noRouteCounter 统计连接请求未注册协议的次数
+type noRouteCounter interface {
+	incNoRoute() uint32
+}
+
+// JoinTaskQueue 加入任务队列，工作池未启动或已停止时任务不再处理
 func (w *worker) JoinTaskQueue(ctx *Context) {
 	if ctx != nil {
-		h := fnv.New32a()
-		h.Write([]byte(ctx.RemoteAddr()))
-		i := int(h.Sum32()) % w.poolSize
+		// 同一连接的任务总是由同一个 worker 处理，保证处理顺序
+		i := int(ctx.ConnID() % uint64(w.poolSize))
+
+		// 加入队列期间持有读锁，Stop 关闭退出信号前所有任务都已进入队列，由 worker 退出时释放
+		w.lock.RLock()
+		defer w.lock.RUnlock()
+
+		if !w.running {
+			log.Println(fmt.Sprintf("[ WORKER ] worker pool is not running, drop task from %s, protocol: %d", ctx.RemoteAddr(), ctx.Protocol()))
+			ctx.done()
+			return
+		}
 
 		log.Println(fmt.Sprintf("[ WORKER ] worker %d serves for %s, protocol: %d, data: %s", i, ctx.RemoteAddr(), ctx.Protocol(), ctx.RawData()))
 		w.taskQueue[i] <- ctx
@@ -0,0 +1,222 @@
+package orbit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockConn struct {
+	Connection
+	id   uint64
+	addr string
+}
+
+func (m *mockConn) ID() uint64         { return m.id }
+func (m *mockConn) RemoteAddr() string { return m.addr }
+
+func TestWorkerStartStop(t *testing.T) {
+	var started, stopped, handled int32
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		atomic.AddInt32(&handled, 1)
+	})
+
+	w := newWorker(options{
+		pool:          4,
+		tasks:         16,
+		router:        r,
+		onWorkerStart: func(wid int) { atomic.AddInt32(&started, 1) },
+		onWorkerStop:  func(wid int) { atomic.AddInt32(&stopped, 1) },
+	})
+
+	// 重复启动和停止都是安全的
+	for round := int32(1); round <= 2; round++ {
+		w.Start()
+		w.Start()
+
+		w.JoinTaskQueue(&Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}})
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&handled) == round }, time.Second, 10*time.Millisecond)
+
+		w.Stop()
+		w.Stop()
+
+		assert.Equal(t, 4*round, atomic.LoadInt32(&started))
+		assert.Equal(t, 4*round, atomic.LoadInt32(&stopped))
+	}
+
+	// 停止后加入任务不会阻塞
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 32; i++ {
+			w.JoinTaskQueue(&Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("join task queue blocked after stop")
+	}
+}
+
+func TestWorkerNotRunning(t *testing.T) {
+	w := newWorker(options{pool: 2, tasks: 4, router: Setup()})
+
+	// 启动前加入任务直接返回并完成任务
+	var tasks sync.WaitGroup
+	tasks.Add(1)
+	done := make(chan struct{})
+	go func() {
+		w.JoinTaskQueue(&Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}, tasks: &tasks})
+		tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("join task queue blocked before start")
+	}
+
+	// 快速重启时旧的 worker 不会读到新的退出信号
+	stopped := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			w.Start()
+			w.Stop()
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("worker pool stop blocked after restart")
+	}
+}
+
+func TestUseSingleWorker(t *testing.T) {
+	var handled int32
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		atomic.AddInt32(&handled, 1)
+	})
+	w := newWorker(options{pool: 1, tasks: 4, router: r})
+	queue := make(chan *Context, 1)
+
+	// 工作池未启动时直接返回
+	done := make(chan struct{})
+	go func() {
+		w.UseSingleWorker(1, queue)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("use single worker blocked before start")
+	}
+
+	// 额外的 worker 随工作池停止退出
+	w.Start()
+	done = make(chan struct{})
+	go func() {
+		w.UseSingleWorker(1, queue)
+		close(done)
+	}()
+	queue <- &Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&handled) == 1 }, time.Second, 10*time.Millisecond)
+
+	w.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("single worker not exit after stop")
+	}
+}
+
+func TestWorkerStopReleaseTasks(t *testing.T) {
+	var once sync.Once
+	started := make(chan struct{})
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		once.Do(func() { close(started) })
+		time.Sleep(100 * time.Millisecond)
+	})
+	w := newWorker(options{pool: 1, tasks: 8, router: r})
+	w.Start()
+
+	// 第一个任务处理中，其余任务在队列中等待
+	var tasks sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		tasks.Add(1)
+		w.JoinTaskQueue(&Context{protocol: 1, conn: &mockConn{addr: "127.0.0.1:1"}, tasks: &tasks})
+	}
+	<-started
+	w.Stop()
+
+	// 停止后队列中未处理的任务也被释放
+	done := make(chan struct{})
+	go func() {
+		tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queued tasks not released after stop")
+	}
+}
+
+type mockCloseConn struct {
+	mockConn
+	closed int32
+}
+
+func (m *mockCloseConn) Close() { atomic.StoreInt32(&m.closed, 1) }
+
+func TestWorkerRecover(t *testing.T) {
+	var handled int32
+	reported := make(chan interface{}, 1)
+
+	r := Setup()
+	r.Handle(1, func(ctx *Context) {
+		panic("boom")
+	})
+	r.Handle(2, func(ctx *Context) {
+		atomic.AddInt32(&handled, 1)
+	})
+
+	w := newWorker(options{
+		pool:   1,
+		tasks:  16,
+		router: r,
+		onPanic: func(ctx *Context, err interface{}, stack []byte) {
+			assert.NotEmpty(t, stack)
+			reported <- err
+		},
+		closeOnPanic: true,
+	})
+	w.Start()
+	defer w.Stop()
+
+	bad := &mockCloseConn{mockConn: mockConn{id: 1, addr: "127.0.0.1:1"}}
+	good := &mockCloseConn{mockConn: mockConn{id: 2, addr: "127.0.0.1:2"}}
+	w.JoinTaskQueue(&Context{protocol: 1, conn: bad})
+	w.JoinTaskQueue(&Context{protocol: 2, conn: good})
+
+	select {
+	case err := <-reported:
+		assert.Equal(t, "boom", err)
+	case <-time.After(time.Second):
+		t.Fatal("panic not reported")
+	}
+
+	// worker 继续处理后续任务，只有引发 panic 的连接被关闭
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&handled) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bad.closed))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&good.closed))
+}